@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alexstory/kanga/data"
+	"github.com/alexstory/kanga/stats"
+)
+
+// bucketing splits entries by when they were logged.
+type bucketing struct {
+	title  string
+	labels []string
+	bucket func(t time.Time) int
+}
+
+var bucketings = map[string]bucketing{
+	"hour": {
+		title:  "HOUR",
+		labels: hourLabels(),
+		bucket: func(t time.Time) int { return t.Hour() },
+	},
+	"weekday": {
+		title:  "WEEKDAY",
+		labels: []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"},
+		// Weeks start on Monday so the weekend stays together.
+		bucket: func(t time.Time) int { return (int(t.Weekday()) + 6) % 7 },
+	},
+	"month": {
+		title: "MONTH",
+		labels: []string{"January", "February", "March", "April", "May", "June",
+			"July", "August", "September", "October", "November", "December"},
+		bucket: func(t time.Time) int { return int(t.Month()) - 1 },
+	},
+}
+
+func hourLabels() []string {
+	labels := make([]string, 24)
+	for h := range labels {
+		labels[h] = fmt.Sprintf("%02d:00-%02d:59", h, h)
+	}
+	return labels
+}
+
+// coinCounts holds the heads and coins flipped in each bucket.
+type coinCounts struct {
+	heads, coins []int
+}
+
+func (c coinCounts) add(bucket, heads, coins int) {
+	c.heads[bucket] += heads
+	c.coins[bucket] += coins
+}
+
+// Breakdown shows the heads rate of every coin flipped by the given card, or
+// by all cards, per hour, weekday or month in the time zone of inv, and tests
+// whether the rate differs between them.
+func Breakdown(ctx context.Context, store data.Store, inv *Invocation, args []string, by string, filter data.Filter) error {
+	b, ok := bucketings[by]
+	if !ok {
+		return usageError("breakdown", "invalid value %q for --by: must be hour, weekday or month", by)
+	}
+	cards := []data.TableType{data.Kanga, data.Egg, data.Misty}
+	title := "ALL COINS"
+	if len(args) > 0 {
+		card, ok := parseCard(args[0])
+		if !ok {
+			return usageError("breakdown", "invalid card %q for breakdown command", args[0])
+		}
+		cards = []data.TableType{card}
+		title = cardTitle(card)
+	}
+
+	counts := coinCounts{make([]int, len(b.labels)), make([]int, len(b.labels))}
+	for _, card := range cards {
+		if err := countCoins(ctx, store, card, filter, b, inv.Location, counts); err != nil {
+			return err
+		}
+	}
+
+	var dataPairs []LabelValuePair
+	for i, label := range b.labels {
+		if counts.coins[i] == 0 {
+			continue
+		}
+		lo, hi := stats.WilsonCI(counts.heads[i], counts.coins[i])
+		dataPairs = append(dataPairs, LabelValuePair{label, fmt.Sprintf("%6.2f%% [%5.2f%%, %5.2f%%] n=%d",
+			percentage(counts.heads[i], counts.coins[i]), lo*100, hi*100, counts.coins[i])})
+	}
+	if len(dataPairs) == 0 {
+		dataPairs = append(dataPairs, LabelValuePair{"Note", "no entries to break down"})
+		printTable(title+" BY "+b.title, dataPairs)
+		return nil
+	}
+
+	chi2, df, p, minExpected := stats.ChiSquareHomogeneity(counts.heads, counts.coins)
+	dataPairs = append(dataPairs,
+		LabelValuePair{"Chi-square", fmt.Sprintf("%s (df %d)", formatStat(chi2, "%.3f"), df)},
+		LabelValuePair{"Heterogeneity p-value", formatStat(p, "%.4f")},
+	)
+	if df > 0 && minExpected < 5 {
+		dataPairs = append(dataPairs, LabelValuePair{"Note", "too few coins in some buckets for a reliable test"})
+	}
+	printTable(title+" BY "+b.title, dataPairs)
+	return nil
+}
+
+// countCoins adds the coins flipped by a card to their bucket, bucketing
+// entries in loc. Misty flips until tails, so every attempt is its heads
+// plus one tails.
+func countCoins(ctx context.Context, store data.Store, card data.TableType, filter data.Filter, b bucketing, loc *time.Location, counts coinCounts) error {
+	bucket := func(createdAt string) (int, error) {
+		t, err := data.ParseTime(createdAt)
+		if err != nil {
+			return 0, err
+		}
+		return b.bucket(t.In(loc)), nil
+	}
+
+	switch card {
+	case data.Kanga:
+		entries, err := store.FlipEntries(ctx, filter, 0)
+		if err != nil {
+			return fmt.Errorf("failed to get flips: %w", err)
+		}
+		for _, e := range entries {
+			i, err := bucket(e.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("flip #%d: %w", e.ID, err)
+			}
+			counts.add(i, e.Heads1+e.Heads2, 2)
+		}
+	case data.Egg:
+		entries, err := store.EggEntries(ctx, filter, 0)
+		if err != nil {
+			return fmt.Errorf("failed to get egg entries: %w", err)
+		}
+		for _, e := range entries {
+			i, err := bucket(e.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("egg #%d: %w", e.ID, err)
+			}
+			counts.add(i, e.Heads, 1)
+		}
+	case data.Misty:
+		entries, err := store.MistyEntries(ctx, filter, 0)
+		if err != nil {
+			return fmt.Errorf("failed to get misty entries: %w", err)
+		}
+		for _, e := range entries {
+			i, err := bucket(e.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("misty #%d: %w", e.ID, err)
+			}
+			counts.add(i, e.Heads, e.Heads+1)
+		}
+	}
+	return nil
+}
@@ -1,13 +1,96 @@
 package cmd
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"strings"
+	"time"
 
 	"github.com/alexstory/kanga/data"
 )
 
+// Exit codes returned by the kanga binary, so scripts can tell failures
+// apart.
+const (
+	ExitOK       = 0
+	ExitError    = 1 // a database operation failed
+	ExitUsage    = 2 // bad command line or invalid value
+	ExitDatabase = 3 // the database could not be opened
+	ExitIO       = 4 // reading or writing a file failed
+	ExitCanceled = 5 // the command timed out or was interrupted
+)
+
+// DefaultTimeout bounds how long a single CLI command may spend on the
+// database before it is canceled.
+const DefaultTimeout = time.Minute
+
+// ErrUsage is returned when a command is invoked with bad arguments.
+var ErrUsage = errors.New("usage error")
+
+// usageError returns an error wrapping ErrUsage that points the user to the
+// help of the given command.
+func usageError(command, format string, args ...any) error {
+	return fmt.Errorf("%w: %s (see `kanga help %s`)", ErrUsage, fmt.Sprintf(format, args...), command)
+}
+
+// ExitCode returns the process exit code for an error returned by a command.
+func ExitCode(err error) int {
+	var pathErr *fs.PathError
+	var storeErr *storeError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, ErrUsage), errors.Is(err, data.ErrInvalidValue), errors.Is(err, data.ErrNotFound):
+		return ExitUsage
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return ExitCanceled
+	case errors.As(err, &storeErr):
+		return ExitDatabase
+	case errors.As(err, &pathErr):
+		return ExitIO
+	}
+	return ExitError
+}
+
+func parseCard(name string) (data.TableType, bool) {
+	switch name {
+	case "kanga", "kangaskhan":
+		return data.Kanga, true
+	case "egg", "exeggutor":
+		return data.Egg, true
+	case "misty":
+		return data.Misty, true
+	}
+	return 0, false
+}
+
+// cardName returns the name a card is given on the command line.
+func cardName(card data.TableType) string {
+	switch card {
+	case data.Kanga:
+		return "kanga"
+	case data.Egg:
+		return "egg"
+	case data.Misty:
+		return "misty"
+	}
+	return ""
+}
+
+func cardTitle(card data.TableType) string {
+	switch card {
+	case data.Kanga:
+		return "KANGASKHAN"
+	case data.Egg:
+		return "EXEGGUTOR"
+	case data.Misty:
+		return "MISTY"
+	}
+	return ""
+}
+
 type LabelValuePair struct {
 	Label string
 	Value string
@@ -47,11 +130,99 @@ func printTable(title string, dataPairs []LabelValuePair) {
 	fmt.Println(border)
 }
 
-func ReadCsv(db *sql.DB, folder string, table string) {
-	err := data.ReadCsv(db, folder, table)
+// printGrid prints a table with a header row and any number of columns.
+// The first column is left aligned like the labels of printTable, the others
+// are right aligned.
+func printGrid(title string, header []string, rows [][]string) {
+	widths := make([]int, len(header))
+	for _, row := range append([][]string{header}, rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	widths[0] = max(widths[0], 24)
+
+	format := func(row []string) string {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			if i == 0 {
+				cells[i] = fmt.Sprintf("%-*s", widths[i], cell)
+			} else {
+				cells[i] = fmt.Sprintf("%*s", widths[i], cell)
+			}
+		}
+		return "| " + strings.Join(cells, " | ") + " |"
+	}
+
+	headerLine := format(header)
+	inner := len(headerLine) - 2
+	if len(title) > inner {
+		widths[0] += len(title) - inner
+		headerLine = format(header)
+		inner = len(title)
+	}
+	border := "+" + strings.Repeat("-", inner) + "+"
+	titlePadding := (inner - len(title)) / 2
+	fmt.Println(border)
+	fmt.Println("|" + strings.Repeat(" ", titlePadding) + title + strings.Repeat(" ", inner-len(title)-titlePadding) + "|")
+	fmt.Println(border)
+	fmt.Println(headerLine)
+	fmt.Println(border)
+	for _, row := range rows {
+		fmt.Println(format(row))
+	}
+	fmt.Println(border)
+}
+
+// ReadCsv imports CSV files into a store. Rows without a profile are
+// imported for the profile in use. When a row is invalid, nothing is
+// imported unless skipInvalid is set.
+func ReadCsv(ctx context.Context, store data.Importer, inv *Invocation, folder string, tables map[data.TableType]bool, skipInvalid bool) error {
+	if err := inv.requireProfile("read-csv"); err != nil {
+		return err
+	}
+	result, err := store.ReadCsv(ctx, folder, tables, skipInvalid, inv.Profile)
+	var importErr *data.ImportError
+	if errors.As(err, &importErr) {
+		printRejected(importErr.Rows)
+		return fmt.Errorf("%w: %d rows, nothing imported (use --skip-invalid to import the valid ones)",
+			data.ErrInvalidValue, len(importErr.Rows))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read CSV: %w", err)
+	}
+
+	if len(result.Rejected) > 0 {
+		printRejected(result.Rejected)
+	}
+	dataPairs := []LabelValuePair{
+		{"Flips", fmt.Sprintf("%d", result.Imported[data.Kanga])},
+		{"Exeggutor entries", fmt.Sprintf("%d", result.Imported[data.Egg])},
+		{"Misty entries", fmt.Sprintf("%d", result.Imported[data.Misty])},
+		{"Games", fmt.Sprintf("%d", result.Games)},
+		{"Rejected rows", fmt.Sprintf("%d", len(result.Rejected))},
+	}
+	for _, path := range result.RejectFiles {
+		dataPairs = append(dataPairs, LabelValuePair{"Rejects written to", path})
+	}
+	printTable("IMPORTED", dataPairs)
+	fmt.Printf("Data read from %s\n", folder)
+	return nil
+}
+
+func printRejected(rows []data.RowError) {
+	dataPairs := make([]LabelValuePair, len(rows))
+	for i, row := range rows {
+		dataPairs[i] = LabelValuePair{fmt.Sprintf("%s:%d", row.File, row.Line), row.Reason}
+	}
+	printTable("REJECTED ROWS", dataPairs)
+}
+
+func DumpCsv(ctx context.Context, store data.Store, folder string, tables map[data.TableType]bool) error {
+	err := data.DumpCsv(ctx, store, folder, tables)
 	if err != nil {
-		fmt.Printf("Failed to read CSV: %v\n", err)
-	} else {
-		fmt.Printf("Data read from %s\n", folder)
+		return fmt.Errorf("failed to dump CSV: %w", err)
 	}
+	fmt.Printf("Data dumped to %s\n", folder)
+	return nil
 }
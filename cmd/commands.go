@@ -0,0 +1,592 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/alexstory/kanga/data"
+)
+
+var cardChoices = []Choice{
+	{"kanga", "Kangaskhan"},
+	{"egg", "Exeggutor"},
+	{"misty", "Misty"},
+}
+
+func init() {
+	commands = []*Command{
+		{
+			Name:    "heads",
+			Summary: "Show heads info",
+			Setup:   filterRunner(Heads),
+		},
+		{
+			Name:    "tails",
+			Summary: "Show tails info",
+			Setup:   filterRunner(Tails),
+		},
+		{
+			Name:    "stats",
+			Summary: "Show statistics",
+			Setup:   filterRunner(Stats),
+		},
+		flipCommand("TT", "Log a double tails flip", data.TT),
+		flipCommand("HH", "Log a double heads flip", data.HH),
+		flipCommand("HT", "Log a heads-tails flip", data.HT),
+		flipCommand("TH", "Log a tails-heads flip", data.TH),
+		{
+			Name:    "egg",
+			Usage:   "<command>",
+			Summary: "Log an exeggutor entry or show stats",
+			Choices: []Choice{
+				{"H", "Log a heads"},
+				{"T", "Log a tails"},
+				{"HX", "Log a heads, but... the result didn't really matter"},
+				{"TX", "Log a tails, but... the result didn't really matter"},
+				{"stats", "Show exeggutor statistics"},
+				{"mattered", "Compare heads when it mattered against when it didn't"},
+				{"undo", "Undo the last exeggutor entry"},
+			},
+			Setup: func(fs *flag.FlagSet) Runner {
+				readMeta := entryFlags(fs)
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					meta, err := readMeta(inv)
+					if err != nil {
+						return err
+					}
+					return Egg(ctx, store, inv, args, meta)
+				}
+			},
+		},
+		{
+			Name:    "misty",
+			Usage:   "<command>",
+			Summary: "Log a misty entry or show stats",
+			Choices: []Choice{
+				{"<number>", "Log a misty entry with the specified number of heads"},
+				{"stats", "Show misty statistics"},
+				{"undo", "Undo the last misty entry"},
+			},
+			Setup: func(fs *flag.FlagSet) Runner {
+				readMeta := entryFlags(fs)
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					meta, err := readMeta(inv)
+					if err != nil {
+						return err
+					}
+					return Misty(ctx, store, inv, args, meta)
+				}
+			},
+		},
+		{
+			Name:    "log",
+			Usage:   "[card] <results...>",
+			Summary: "Log many results at once",
+			Details: []string{
+				"Log many results in a single transaction. Nothing is written unless every",
+				"result is valid. A card name switches which card the following results",
+				"are for; results default to Kangaskhan flips. Without results, or with",
+				"\"-\", results are read from stdin, one line at a time.",
+				"  kanga log HH HT TT",
+				"  kanga log egg HHXT      (H, HX, T)",
+				"  kanga log misty 0 2 1",
+				"  kanga log HH egg TX misty 3",
+				"The --note, --tag and --at flags apply to every result.",
+			},
+			Choices: []Choice{
+				{"kanga", "Log Kangaskhan flips (HH, HT, TH, TT)"},
+				{"egg", "Log exeggutor results (H, HX, T, TX, written back to back)"},
+				{"misty", "Log misty heads counts"},
+			},
+			Setup: func(fs *flag.FlagSet) Runner {
+				readMeta := metaFlags(fs)
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					meta, err := readMeta(inv)
+					if err != nil {
+						return err
+					}
+					return Log(ctx, store, args, meta)
+				}
+			},
+		},
+		{
+			Name:    "profile",
+			Usage:   "<command>",
+			Summary: "Manage player profiles",
+			Details: []string{
+				"Entries are logged for the profile in use, and stats only count its entries.",
+				"Give --profile to any command to act as another profile once, or",
+				"--profile all to show stats over every profile.",
+			},
+			Choices: []Choice{
+				{"add", "Add a profile"},
+				{"use", "Log entries for a profile from now on"},
+				{"list", "List profiles and their number of entries"},
+			},
+			Setup: argsRunner(func(ctx context.Context, store data.Store, _ *Invocation, args []string) error {
+				return Profile(ctx, store, args)
+			}),
+		},
+		{
+			Name:    "deck",
+			Usage:   "<command>",
+			Summary: "Manage decks and compare their damage",
+			Details: []string{
+				"Entries are logged with the deck the profile uses, if any. Give --deck to",
+				"any command to log with another deck once, or to only count its entries",
+				"in stats (--deck none counts entries logged without a deck).",
+				"The list compares the damage per attack of every deck.",
+			},
+			Choices: []Choice{
+				{"add", "Add a deck"},
+				{"use", "Log entries of the profile with a deck from now on, or none"},
+				{"list", "Compare the coin-flip damage of every deck"},
+			},
+			Setup: func(fs *flag.FlagSet) Runner {
+				readFilter := filterFlags(fs)
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					return Deck(ctx, store, inv, args, readFilter(inv))
+				}
+			},
+		},
+		{
+			Name:    "game",
+			Usage:   "<command>",
+			Summary: "Log the result of a game or relate luck to results",
+			Details: []string{
+				"Close the current game with its result, linking it every entry of the profile",
+				"logged since the previous game (at most 3 hours back for the first game, or",
+				"since --since). The report fits a logistic regression of winning on each",
+				"game's luck: how many standard deviations its heads, over every card, are",
+				"above what fair coins give. Draws are left out of the fit. --vs sets the",
+				"opponent of the game and of linked entries logged without one.",
+			},
+			Choices: []Choice{
+				{"win", "Close the current game as a win"},
+				{"loss", "Close the current game as a loss"},
+				{"draw", "Close the current game as a draw"},
+				{"report", "Relate per-game luck to game results"},
+			},
+			Setup: func(fs *flag.FlagSet) Runner {
+				readMeta := entryFlags(fs)
+				since := fs.String("since", "", "When the game started, e.g. -45m (default: when the previous game ended)")
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					meta, err := readMeta(inv)
+					if err != nil {
+						return err
+					}
+					return Game(ctx, store, inv, args, meta, *since)
+				}
+			},
+		},
+		{
+			Name:    "matchups",
+			Summary: "Compare damage and win rates against each opponent",
+			Details: []string{
+				"Show, for every opponent archetype given with --vs, the Kangaskhan and",
+				"Exeggutor attacks made against it with their average coin-flip damage, the",
+				"Misty attempts with their average energy, and the record and win rate of the",
+				"games played against it when games are logged. Entries and games logged",
+				"without an opponent are listed as unknown.",
+			},
+			Setup: func(fs *flag.FlagSet) Runner {
+				readFilter := filterFlags(fs)
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					return Matchups(ctx, store, readFilter(inv))
+				}
+			},
+		},
+		{
+			Name:    "history",
+			Usage:   "[card]",
+			Summary: "List logged entries with their ids",
+			Details: []string{"List the latest entries of a card, or of every card, with their ids, local time, tags and notes"},
+			Choices: cardChoices,
+			Setup: func(fs *flag.FlagSet) Runner {
+				limit := fs.Int("limit", 20, "Number of entries to list per card (0 lists all)")
+				readFilter := filterFlags(fs)
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					return History(ctx, store, inv, args, *limit, readFilter(inv))
+				}
+			},
+		},
+		{
+			Name:    "edit",
+			Usage:   "<card> <id> <value>",
+			Summary: "Change the result of an entry",
+			Details: []string{
+				"Change the result of an entry, showing it before and after. The value is",
+				"typed the same way as when logging: HH/HT/TH/TT, H/HX/T/TX or a number.",
+				"Only entries of the profile in use can be changed, unless with --profile all.",
+			},
+			Choices: cardChoices,
+			Setup: func(fs *flag.FlagSet) Runner {
+				dryRun := fs.Bool("dry-run", false, "Only show the change")
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					return Edit(ctx, store, inv, args, *dryRun)
+				}
+			},
+		},
+		{
+			Name:    "delete",
+			Usage:   "<card> <id>",
+			Summary: "Delete an entry",
+			Details: []string{"Delete an entry of the profile in use, or of any profile with --profile all, showing it first"},
+			Choices: cardChoices,
+			Setup: func(fs *flag.FlagSet) Runner {
+				dryRun := fs.Bool("dry-run", false, "Only show the change")
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					return Delete(ctx, store, inv, args, *dryRun)
+				}
+			},
+		},
+		{
+			Name:    "independence",
+			Summary: "Test whether coin results are independent",
+			Details: []string{
+				"Test whether Kangaskhan's first and second coin are independent, and",
+				"whether results are correlated across consecutive attacks",
+			},
+			Setup: filterRunner(Independence),
+		},
+		{
+			Name:    "mattered",
+			Usage:   "[card]",
+			Summary: "Compare heads when it mattered against when it didn't",
+			Details: []string{
+				"Compare the heads rate of flips that mattered against the ones that didn't,",
+				"for every card that records it (currently: egg)",
+			},
+			Choices: []Choice{{"egg", "Exeggutor"}},
+			Setup: func(fs *flag.FlagSet) Runner {
+				readFilter := filterFlags(fs)
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					return Mattered(ctx, store, args, readFilter(inv))
+				}
+			},
+		},
+		{
+			Name:    "breakdown",
+			Usage:   "[card]",
+			Summary: "Break the heads rate down by hour, weekday or month",
+			Details: []string{
+				"Show the heads rate of every coin flipped by the given card, or by all cards,",
+				"per hour, weekday or month with 95% Wilson intervals, and test whether it",
+				"differs between them. Entries are bucketed in the local time zone (see --tz)",
+			},
+			Choices: cardChoices,
+			Setup: func(fs *flag.FlagSet) Runner {
+				by := fs.String("by", "hour", "Bucket entries by hour, weekday or month")
+				readFilter := filterFlags(fs)
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					return Breakdown(ctx, store, inv, args, *by, readFilter(inv))
+				}
+			},
+		},
+		{
+			Name:    "compare",
+			Usage:   "<selector> <selector>",
+			Summary: "Compare the stats of two sets of entries",
+			Details: []string{
+				"Show the stats of the entries picked by two selectors side by side, with the",
+				"difference of every rate and the p-value of a two proportion z-test. A",
+				"selector is a comma separated list of terms entries must all match:",
+				"  all                  every entry of the profile in use",
+				"  tag:NAME             entries tagged NAME",
+				"  profile:NAME         entries of a profile instead of the one in use, or",
+				"                       of every profile with profile:all",
+				"  deck:NAME            entries played with a deck, or without one with deck:none",
+				"  vs:NAME              entries played against an opponent archetype",
+				"  last:SPAN            entries of the last SPAN, e.g. last:30d",
+				"  2026, 2026-09        entries of a year, month or day",
+				"  2026-Q3              entries of a quarter, from July to September",
+				"  FROM..TO             entries from FROM up to TO, either side optional;",
+				"                       dates are read like --at, e.g. 2026-09-01..2026-09-30",
+				"                       (put -- before selectors starting with -)",
+				"e.g. kanga compare tag:tournament tag:casual",
+				"     kanga compare profile:alice profile:bob",
+				"     kanga compare deck:haymaker deck:rain-dance",
+				"     kanga compare 2026-Q2 2026-Q3",
+			},
+			Setup: argsRunner(Compare),
+		},
+		{
+			Name:    "leaderboard",
+			Summary: "Rank profiles by luck",
+			Details: []string{
+				"Rank every profile by the heads rate of all its coins, pulled toward a fair",
+				"coin by --prior coins so small samples don't top the board, Misty average",
+				"energy, Exeggutor damage per attack or longest tails streak. --sort streak",
+				"puts the longest streak first.",
+			},
+			Setup: func(fs *flag.FlagSet) Runner {
+				sortBy := fs.String("sort", "luck", "Rank by luck, misty, egg or streak")
+				prior := fs.Int("prior", 100, "Fair coins the heads rate is pulled toward")
+				format := formatFlag(fs)
+				readFilter := filterFlags(fs)
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					return Leaderboard(ctx, store, *sortBy, *prior, *format, readFilter(inv))
+				}
+			},
+		},
+		{
+			Name:    "simulate",
+			Usage:   "<card>",
+			Summary: "Compare a card's logged results with a simulation",
+			Choices: cardChoices,
+			Setup: func(fs *flag.FlagSet) Runner {
+				n := fs.Int("n", 100000, "Number of attacks to simulate")
+				seed := fs.Uint64("seed", 0, "Random seed, for repeatable runs (default: time based)")
+				readFilter := filterFlags(fs)
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					return Simulate(ctx, store, args, *n, *seed, readFilter(inv))
+				}
+			},
+		},
+		{
+			Name:    "seed",
+			Summary: "Generate a database of random entries",
+			Details: []string{
+				"Generate a database of random flips, exeggutor and misty entries, for demos,",
+				"benchmarks and testing. Entries are spread evenly over --span, ending at --end,",
+				"and the same --seed and --end always generate the same results. The",
+				"database is written to kanga-seed.db next to kanga.db unless --out is given;",
+				"use the global --db flag to run other commands against it. Seeding has no",
+				"time limit unless --timeout is given.",
+			},
+			NoStore:   true,
+			NoTimeout: true,
+			Setup: func(fs *flag.FlagSet) Runner {
+				n := fs.Int("n", 1000, "Number of entries to generate per card")
+				bias := fs.Float64("bias", 0.5, "Probability of a coin landing heads, below 1")
+				seed := fs.Uint64("seed", 1, "Random seed")
+				span := fs.String("span", "30d", "Period the entries are spread over, e.g. 90d or 12h")
+				end := fs.String("end", "2026-01-01T00:00:00Z", "When the span ends, read like --at, e.g. \"2026-10-01\" or -1d")
+				out := fs.String("out", "", "Database file to create (default: kanga-seed.db next to kanga.db)")
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					d, err := parseSpan(*span)
+					if err != nil {
+						return usageError("seed", "--span: %v", err)
+					}
+					t, err := parseAt(*end, inv.now())
+					if err != nil {
+						return usageError("seed", "--end: %v", err)
+					}
+					return Seed(ctx, inv, *out, *n, *bias, *seed, t, d)
+				}
+			},
+		},
+		{
+			Name:    "reset",
+			Summary: "Reset the database",
+			Details: []string{"Delete the entries and games of the profile in use, or of every profile with --profile all"},
+			Setup: storeRunner(func(ctx context.Context, store data.Store, inv *Invocation) error {
+				if err := store.Reset(ctx, inv.Profile); err != nil {
+					return err
+				}
+				if inv.Profile == "" {
+					fmt.Printf("Data reset\n")
+					return nil
+				}
+				fmt.Printf("Data of %s reset\n", inv.Profile)
+				return nil
+			}),
+		},
+		{
+			Name:    "undo",
+			Summary: "Undo the last action",
+			Details: []string{"Delete the last flip logged for the profile in use"},
+			Setup: storeRunner(func(ctx context.Context, store data.Store, inv *Invocation) error {
+				if err := inv.requireProfile("undo"); err != nil {
+					return err
+				}
+				if err := store.Undo(ctx, inv.Profile); err != nil {
+					return err
+				}
+				fmt.Printf("Last flip undone\n")
+				return nil
+			}),
+		},
+		{
+			Name:    "dump-csv",
+			Usage:   "[folder]",
+			Summary: "Dump the data to CSV files",
+			Details: []string{
+				"Dump the data to CSV files in the specified folder (default: current directory).",
+				"Every row keeps its note, tags, profile, game, deck and opponent. When no",
+				"table is selected, the games are written to games.csv too.",
+			},
+			Setup: func(fs *flag.FlagSet) Runner {
+				tables := tableFlags(fs, data.Kanga, data.Egg, data.Misty)
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					return DumpCsv(ctx, store, folderArg(args), tables)
+				}
+			},
+		},
+		{
+			Name:    "read-csv",
+			Usage:   "[folder]",
+			Summary: "Read the data from CSV files",
+			Details: []string{
+				"Read the data from CSV files in the specified folder (default: current directory),",
+				"as written by dump-csv. Every row is checked first and, if any is invalid, each",
+				"one is listed by file and line and nothing is imported. With --skip-invalid, the",
+				"valid rows are imported and the invalid ones are written to a .rejects.csv file",
+				"next to the one they came from. Rows keep the profile, deck and opponent they",
+				"were dumped with; rows without a profile, such as those dumped by older",
+				"versions, are imported for the profile in use. When no table is selected,",
+				"games.csv is read too and the entries are linked to the imported games.",
+			},
+			Setup: func(fs *flag.FlagSet) Runner {
+				tables := tableFlags(fs, data.Kanga, data.Egg, data.Misty)
+				skipInvalid := fs.Bool("skip-invalid", false, "Import the valid rows and write the invalid ones to a .rejects.csv file")
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					importer, err := storeAs[data.Importer]("read-csv", store)
+					if err != nil {
+						return err
+					}
+					return ReadCsv(ctx, importer, inv, folderArg(args), tables, *skipInvalid)
+				}
+			},
+		},
+		{
+			Name:    "db",
+			Aliases: []string{"doctor"},
+			Usage:   "[check|rebuild]",
+			Summary: "Check the database for invalid, duplicated or misplaced data",
+			Details: []string{
+				"Check every table for values out of range, impossible timestamps, duplicated",
+				"rows and schema drift, and the stats counters for drift. With --fix, the",
+				"database is backed up next to it, then every problem that can be is repaired",
+				"in a single transaction.",
+				"Unfiltered stats are read from counters kept up to date by triggers; rebuild",
+				"recomputes them from scratch.",
+			},
+			Choices: []Choice{
+				{"check", "Check the database (default)"},
+				{"rebuild", "Recompute the stats counters from the tables"},
+			},
+			Setup: func(fs *flag.FlagSet) Runner {
+				fix := fs.Bool("fix", false, "Back the database up, then repair the problems found")
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					maintainer, err := storeAs[data.Maintainer]("db", store)
+					if err != nil {
+						return err
+					}
+					return DB(ctx, maintainer, args, *fix)
+				}
+			},
+		},
+		{
+			Name:    "completion",
+			Usage:   "<shell>",
+			Summary: "Print a shell completion script",
+			Details: []string{
+				"Print a shell completion script. To load it:",
+				"  bash: source <(kanga completion bash)",
+				"  zsh:  source <(kanga completion zsh)",
+				"  fish: kanga completion fish | source",
+			},
+			Choices: []Choice{
+				{"bash", "Bash completion"},
+				{"zsh", "Zsh completion"},
+				{"fish", "Fish completion"},
+			},
+			NoStore: true,
+			Setup: func(fs *flag.FlagSet) Runner {
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					return Completion(args)
+				}
+			},
+		},
+		{
+			Name:    "help",
+			Usage:   "[command]",
+			Summary: "Show this help message, or help for a specific command",
+			NoStore: true,
+			Setup: func(fs *flag.FlagSet) Runner {
+				return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+					if len(args) == 0 {
+						PrintHelp("")
+					} else {
+						PrintHelp(args[0])
+					}
+					return nil
+				}
+			},
+		},
+	}
+}
+
+// storeRunner adapts a command that takes no arguments.
+func storeRunner(f func(ctx context.Context, store data.Store, inv *Invocation) error) func(*flag.FlagSet) Runner {
+	return func(*flag.FlagSet) Runner {
+		return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+			return f(ctx, store, inv)
+		}
+	}
+}
+
+// filterRunner adapts a command that takes no arguments and shows
+// statistics filtered with --tag.
+func filterRunner(f func(ctx context.Context, store data.Store, filter data.Filter) error) func(*flag.FlagSet) Runner {
+	return func(fs *flag.FlagSet) Runner {
+		readFilter := filterFlags(fs)
+		return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+			return f(ctx, store, readFilter(inv))
+		}
+	}
+}
+
+// argsRunner adapts a command that takes positional arguments.
+func argsRunner(f func(ctx context.Context, store data.Store, inv *Invocation, args []string) error) func(*flag.FlagSet) Runner {
+	return func(*flag.FlagSet) Runner {
+		return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+			return f(ctx, store, inv, args)
+		}
+	}
+}
+
+func flipCommand(name, summary string, flipType data.FlipType) *Command {
+	return &Command{
+		Name:    name,
+		Aliases: []string{strings.ToLower(name)},
+		Summary: summary,
+		Setup: func(fs *flag.FlagSet) Runner {
+			readMeta := metaFlags(fs)
+			return func(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+				meta, err := readMeta(inv)
+				if err != nil {
+					return err
+				}
+				return InsertFlip(ctx, store, flipType, meta)
+			}
+		},
+	}
+}
+
+// tableFlags registers a --kanga, --egg or --misty flag for each table and
+// returns the map they fill in.
+func tableFlags(fs *flag.FlagSet, tables ...data.TableType) map[data.TableType]bool {
+	selected := make(map[data.TableType]bool)
+	for _, table := range tables {
+		usage := "Operate on " + cardName(table) + " table"
+		if table == data.Egg {
+			usage = "Operate on exeggutor table"
+		}
+		fs.BoolFunc(cardName(table), usage, func(string) error {
+			selected[table] = true
+			return nil
+		})
+	}
+	return selected
+}
+
+func folderArg(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return "."
+}
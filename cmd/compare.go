@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/alexstory/kanga/data"
+	"github.com/alexstory/kanga/stats"
+)
+
+// selection holds the stats of the entries picked by a selector.
+type selection struct {
+	flips data.Stats
+	egg   data.EggStats
+	misty data.MistyStats
+}
+
+func selectStats(ctx context.Context, store data.Store, filter data.Filter) (selection, error) {
+	var s selection
+	var err error
+	if s.flips, err = store.Flips(ctx, filter); err != nil {
+		return s, fmt.Errorf("failed to get flips: %w", err)
+	}
+	if s.egg, err = store.GetEggStats(ctx, filter); err != nil {
+		return s, fmt.Errorf("failed to get egg stats: %w", err)
+	}
+	if s.misty, err = store.GetMistyStats(ctx, filter); err != nil {
+		return s, fmt.Errorf("failed to get misty stats: %w", err)
+	}
+	return s, nil
+}
+
+// coins returns the heads and coins flipped by every card. Misty flips until
+// tails, so every attempt is its heads plus one tails.
+func (s selection) coins() (heads, coins int) {
+	heads = s.flips.TotalHeads + s.egg.TotalHeads + s.misty.TotalHeads
+	coins = s.flips.TotalFlips + s.egg.TotalEntries + s.misty.TotalHeads + s.misty.TotalEntries
+	return heads, coins
+}
+
+// Compare shows the stats of the entries picked by two selectors side by
+// side, with the difference of every rate and the p-value of a two
+// proportion z-test.
+func Compare(ctx context.Context, store data.Store, inv *Invocation, args []string) error {
+	if len(args) != 2 {
+		return usageError("compare", "expected two selectors, got %d", len(args))
+	}
+	var sel [2]selection
+	for i, arg := range args {
+		filter, err := parseSelector(arg, data.Filter{Profile: inv.Profile, Deck: inv.DeckFilter}, inv.now())
+		if err != nil {
+			return usageError("compare", "%v", err)
+		}
+		if sel[i], err = selectStats(ctx, store, filter); err != nil {
+			return err
+		}
+	}
+	a, b := sel[0], sel[1]
+
+	count := func(label string, x, y int) []string {
+		return []string{label, fmt.Sprintf("%d", x), fmt.Sprintf("%d", y), fmt.Sprintf("%+d", x-y), ""}
+	}
+	rate := func(label string, x1, n1, x2, n2 int) []string {
+		row := []string{label, formatRate(x1, n1), formatRate(x2, n2), "n/a", "n/a"}
+		if n1 > 0 && n2 > 0 {
+			row[3] = fmt.Sprintf("%+.2f%%", percentage(x1, n1)-percentage(x2, n2))
+			_, p := stats.TwoProportionZ(x1, n1, x2, n2)
+			row[4] = formatStat(p, "%.4f")
+		}
+		return row
+	}
+	mean := func(label string, sum1, n1, sum2, n2 int) []string {
+		row := []string{label, "n/a", "n/a", "n/a", ""}
+		m1, m2 := math.NaN(), math.NaN()
+		if n1 > 0 {
+			m1 = float64(sum1) / float64(n1)
+			row[1] = fmt.Sprintf("%.2f", m1)
+		}
+		if n2 > 0 {
+			m2 = float64(sum2) / float64(n2)
+			row[2] = fmt.Sprintf("%.2f", m2)
+		}
+		row[3] = formatStat(m1-m2, "%+.2f")
+		return row
+	}
+
+	headsA, coinsA := a.coins()
+	headsB, coinsB := b.coins()
+	rows := [][]string{
+		count("Kangaskhan coins", a.flips.TotalFlips, b.flips.TotalFlips),
+		rate("Kangaskhan heads", a.flips.TotalHeads, a.flips.TotalFlips, b.flips.TotalHeads, b.flips.TotalFlips),
+		rate("Double heads", a.flips.DoubleHeads, a.flips.TotalFlips/2, b.flips.DoubleHeads, b.flips.TotalFlips/2),
+		rate("Double tails", a.flips.DoubleTails, a.flips.TotalFlips/2, b.flips.DoubleTails, b.flips.TotalFlips/2),
+		count("Exeggutor flips", a.egg.TotalEntries, b.egg.TotalEntries),
+		rate("Exeggutor heads", a.egg.TotalHeads, a.egg.TotalEntries, b.egg.TotalHeads, b.egg.TotalEntries),
+		count("Misty attempts", a.misty.TotalEntries, b.misty.TotalEntries),
+		rate("Misty heads", a.misty.TotalHeads, a.misty.TotalHeads+a.misty.TotalEntries,
+			b.misty.TotalHeads, b.misty.TotalHeads+b.misty.TotalEntries),
+		mean("Misty mean energy", a.misty.TotalHeads, a.misty.TotalEntries, b.misty.TotalHeads, b.misty.TotalEntries),
+		count("All coins", coinsA, coinsB),
+		rate("All heads", headsA, coinsA, headsB, coinsB),
+	}
+	printGrid("COMPARISON", []string{"", args[0], args[1], "Difference", "p-value"}, rows)
+	return nil
+}
+
+// formatRate formats x/n as a percentage, or "n/a" when n is 0.
+func formatRate(x, n int) string {
+	if n == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.2f%%", percentage(x, n))
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Completion writes the completion script for the given shell to stdout.
+// The scripts are generated from the command registry, so they always match
+// the commands, choices and flags kanga accepts.
+func Completion(args []string) error {
+	if len(args) < 1 {
+		PrintHelp("completion")
+		return usageError("completion", "missing shell")
+	}
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(os.Stdout)
+	case "zsh":
+		writeZshCompletion(os.Stdout)
+	case "fish":
+		writeFishCompletion(os.Stdout)
+	default:
+		return usageError("completion", "unsupported shell %q", args[0])
+	}
+	return nil
+}
+
+// completionChoices returns the values completed as a command's first
+// argument, leaving out placeholders such as "<number>".
+func completionChoices(c *Command) []Choice {
+	if c.Name == "help" {
+		var choices []Choice
+		for _, other := range commands {
+			choices = append(choices, Choice{other.Name, other.Summary})
+		}
+		return choices
+	}
+	var choices []Choice
+	for _, choice := range c.Choices {
+		if !strings.HasPrefix(choice.Name, "<") {
+			choices = append(choices, choice)
+		}
+	}
+	return choices
+}
+
+// completionFlags returns the flags accepted by a command, global ones
+// included.
+func completionFlags(c *Command) []*flag.Flag {
+	var flags []*flag.Flag
+	c.declaredFlags().VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+	globalFlagSet().VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+	return flags
+}
+
+// valueFlags returns every flag, declared by any command or global, that
+// expects a value, so completion can skip over it.
+func valueFlags() []string {
+	seen := make(map[string]bool)
+	var flags []string
+	add := func(f *flag.Flag) {
+		if !isBoolFlag(f) && !seen[f.Name] {
+			seen[f.Name] = true
+			flags = append(flags, "--"+f.Name)
+		}
+	}
+	globalFlagSet().VisitAll(add)
+	for _, c := range commands {
+		c.declaredFlags().VisitAll(add)
+	}
+	return flags
+}
+
+func commandNames(c *Command) []string {
+	return append([]string{c.Name}, c.Aliases...)
+}
+
+// singleQuote quotes s for bash and zsh.
+func singleQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func writeBashCompletion(w io.Writer) {
+	var names []string
+	for _, c := range commands {
+		names = append(names, commandNames(c)...)
+	}
+	var globals []string
+	globalFlagSet().VisitAll(func(f *flag.Flag) {
+		globals = append(globals, "--"+f.Name)
+	})
+
+	fmt.Fprintln(w, "# bash completion for kanga")
+	fmt.Fprintln(w, "# Load with: source <(kanga completion bash)")
+	fmt.Fprintln(w, "_kanga() {")
+	fmt.Fprintln(w, `    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}" cmd="" words="" i`)
+	fmt.Fprintf(w, "    local valueflags=%s\n", singleQuote(" "+strings.Join(valueFlags(), " ")+" "))
+	fmt.Fprintln(w, `    if [[ $valueflags == *" $prev "* ]]; then`)
+	fmt.Fprintln(w, `        COMPREPLY=()`)
+	fmt.Fprintln(w, `        return`)
+	fmt.Fprintln(w, `    fi`)
+	fmt.Fprintln(w, `    for ((i = 1; i < COMP_CWORD; i++)); do`)
+	fmt.Fprintln(w, `        case "${COMP_WORDS[i]}" in`)
+	fmt.Fprintln(w, `            -*) [[ $valueflags == *" ${COMP_WORDS[i]} "* ]] && ((i++)) ;;`)
+	fmt.Fprintln(w, `            *) cmd="${COMP_WORDS[i]}"; break ;;`)
+	fmt.Fprintln(w, `        esac`)
+	fmt.Fprintln(w, `    done`)
+	fmt.Fprintln(w, `    case "$cmd" in`)
+	fmt.Fprintf(w, "        \"\") words=%s ;;\n", singleQuote(strings.Join(append(names, globals...), " ")))
+	for _, c := range commands {
+		var words []string
+		for _, choice := range completionChoices(c) {
+			words = append(words, choice.Name)
+		}
+		for _, f := range completionFlags(c) {
+			words = append(words, "--"+f.Name)
+		}
+		fmt.Fprintf(w, "        %s) words=%s ;;\n", strings.Join(commandNames(c), "|"), singleQuote(strings.Join(words, " ")))
+	}
+	fmt.Fprintln(w, `    esac`)
+	fmt.Fprintln(w, `    COMPREPLY=($(compgen -W "$words" -- "$cur"))`)
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "complete -F _kanga kanga")
+}
+
+// zshItem formats a value and its description for _describe, which splits
+// on the first unescaped colon.
+func zshItem(name, help string) string {
+	return singleQuote(strings.ReplaceAll(name, ":", `\:`) + ":" + help)
+}
+
+func writeZshCompletion(w io.Writer) {
+	fmt.Fprintln(w, "#compdef kanga")
+	fmt.Fprintln(w, "# zsh completion for kanga")
+	fmt.Fprintln(w, "# Load with: source <(kanga completion zsh)")
+	fmt.Fprintln(w, "_kanga() {")
+	fmt.Fprintln(w, "    local cmd i")
+	fmt.Fprintln(w, "    local -a items flags")
+	fmt.Fprintf(w, "    local valueflags=%s\n", singleQuote(" "+strings.Join(valueFlags(), " ")+" "))
+	fmt.Fprintln(w, `    [[ $valueflags == *" ${words[CURRENT-1]} "* ]] && return`)
+	fmt.Fprintln(w, "    for ((i = 2; i < CURRENT; i++)); do")
+	fmt.Fprintln(w, "        if [[ ${words[i]} == -* ]]; then")
+	fmt.Fprintln(w, `            [[ $valueflags == *" ${words[i]} "* ]] && ((i++))`)
+	fmt.Fprintln(w, "        else")
+	fmt.Fprintln(w, "            cmd=${words[i]}")
+	fmt.Fprintln(w, "            break")
+	fmt.Fprintln(w, "        fi")
+	fmt.Fprintln(w, "    done")
+	fmt.Fprintln(w, "    case $cmd in")
+	fmt.Fprintln(w, "        '')")
+	fmt.Fprint(w, "            items=(")
+	for _, c := range commands {
+		for _, name := range commandNames(c) {
+			fmt.Fprint(w, " ", zshItem(name, c.Summary))
+		}
+	}
+	fmt.Fprintln(w, " )")
+	fmt.Fprint(w, "            flags=(")
+	globalFlagSet().VisitAll(func(f *flag.Flag) {
+		fmt.Fprint(w, " ", zshItem("--"+f.Name, f.Usage))
+	})
+	fmt.Fprintln(w, " )")
+	fmt.Fprintln(w, "            ;;")
+	for _, c := range commands {
+		fmt.Fprintf(w, "        %s)\n", strings.Join(commandNames(c), "|"))
+		fmt.Fprint(w, "            items=(")
+		for _, choice := range completionChoices(c) {
+			fmt.Fprint(w, " ", zshItem(choice.Name, choice.Help))
+		}
+		fmt.Fprintln(w, " )")
+		fmt.Fprint(w, "            flags=(")
+		for _, f := range completionFlags(c) {
+			fmt.Fprint(w, " ", zshItem("--"+f.Name, f.Usage))
+		}
+		fmt.Fprintln(w, " )")
+		fmt.Fprintln(w, "            ;;")
+	}
+	fmt.Fprintln(w, "    esac")
+	fmt.Fprintln(w, "    if [[ $PREFIX == -* ]]; then")
+	fmt.Fprintln(w, "        _describe 'flag' flags")
+	fmt.Fprintln(w, "    else")
+	fmt.Fprintln(w, "        _describe 'argument' items")
+	fmt.Fprintln(w, "    fi")
+	fmt.Fprintln(w, "}")
+	fmt.Fprintln(w, "compdef _kanga kanga")
+}
+
+// fishQuote quotes s for fish, where only \ and ' are special inside single
+// quotes.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprintln(w, "# fish completion for kanga")
+	fmt.Fprintln(w, "# Load with: kanga completion fish | source")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "# Without arguments, succeeds while no command has been typed yet;")
+	fmt.Fprintln(w, "# otherwise succeeds when the command is one of the arguments.")
+	fmt.Fprintln(w, "function __kanga_using_command")
+	fmt.Fprintln(w, "    set -l tokens (commandline -opc)")
+	fmt.Fprintln(w, "    set -e tokens[1]")
+	fmt.Fprintln(w, "    set -l cmd ''")
+	fmt.Fprintln(w, "    while set -q tokens[1]")
+	fmt.Fprintln(w, "        set -l t $tokens[1]")
+	fmt.Fprintln(w, "        set -e tokens[1]")
+	fmt.Fprintf(w, "        if contains -- $t %s\n", strings.Join(valueFlags(), " "))
+	fmt.Fprintln(w, "            set -e tokens[1]")
+	fmt.Fprintln(w, "        else if not string match -q -- '-*' $t")
+	fmt.Fprintln(w, "            set cmd $t")
+	fmt.Fprintln(w, "            break")
+	fmt.Fprintln(w, "        end")
+	fmt.Fprintln(w, "    end")
+	fmt.Fprintln(w, "    if set -q argv[1]")
+	fmt.Fprintln(w, `        contains -- "$cmd" $argv`)
+	fmt.Fprintln(w, "    else")
+	fmt.Fprintln(w, `        test -z "$cmd"`)
+	fmt.Fprintln(w, "    end")
+	fmt.Fprintln(w, "end")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "complete -c kanga -f")
+	globalFlagSet().VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(w, "complete -c kanga -l %s -r -d %s\n", f.Name, fishQuote(f.Usage))
+	})
+	for _, c := range commands {
+		for _, name := range commandNames(c) {
+			fmt.Fprintf(w, "complete -c kanga -n __kanga_using_command -a %s -d %s\n", fishQuote(name), fishQuote(c.Summary))
+		}
+	}
+	for _, c := range commands {
+		using := fishQuote("__kanga_using_command " + strings.Join(commandNames(c), " "))
+		for _, choice := range completionChoices(c) {
+			fmt.Fprintf(w, "complete -c kanga -n %s -a %s -d %s\n", using, fishQuote(choice.Name), fishQuote(choice.Help))
+		}
+		c.declaredFlags().VisitAll(func(f *flag.Flag) {
+			value := " -r"
+			if isBoolFlag(f) {
+				value = ""
+			}
+			fmt.Fprintf(w, "complete -c kanga -n %s -l %s%s -d %s\n", using, f.Name, value, fishQuote(f.Usage))
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexstory/kanga/data"
+)
+
+// DB runs a database maintenance subcommand, check by default.
+func DB(ctx context.Context, store data.Maintainer, args []string, fix bool) error {
+	sub := "check"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+	switch sub {
+	case "check":
+		return Check(ctx, store, fix)
+	case "rebuild":
+		if err := store.RebuildCounters(ctx); err != nil {
+			return fmt.Errorf("failed to rebuild stats counters: %w", err)
+		}
+		fmt.Println("Stats counters rebuilt")
+		return nil
+	}
+	return usageError("db", "invalid argument %q for db command", sub)
+}
+
+// Check lists the problems found in the database. With fix, the database is
+// backed up and every problem that can be is repaired.
+func Check(ctx context.Context, store data.Maintainer, fix bool) error {
+	var problems []data.Problem
+	var backup string
+	var err error
+	if fix {
+		problems, backup, err = store.Repair(ctx)
+	} else {
+		problems, err = store.Check(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check the database: %w", err)
+	}
+	if backup != "" {
+		fmt.Printf("Backup written to %s\n", backup)
+	}
+
+	if len(problems) == 0 {
+		printTable("DATABASE CHECK", []LabelValuePair{{"Problems", "none"}})
+		return nil
+	}
+	dataPairs := make([]LabelValuePair, len(problems))
+	manual := 0
+	for i, p := range problems {
+		label := p.Table
+		if p.ID != 0 {
+			label = fmt.Sprintf("%s #%d", p.Table, p.ID)
+		}
+		action := p.Fix
+		switch {
+		case p.Fix == "":
+			action = "fix by hand"
+			manual++
+		case fix:
+			action = "fixed: " + p.Fix
+		}
+		dataPairs[i] = LabelValuePair{label, p.Message + " (" + action + ")"}
+	}
+	printTable("DATABASE CHECK", dataPairs)
+
+	fixable := len(problems) - manual
+	switch {
+	case !fix && fixable > 0:
+		return fmt.Errorf("problems found: %d, run `kanga db check --fix` to repair %d of them", len(problems), fixable)
+	case !fix:
+		return fmt.Errorf("problems found: %d, to fix by hand", len(problems))
+	case manual > 0:
+		return fmt.Errorf("problems fixed: %d, left to fix by hand: %d", fixable, manual)
+	}
+	fmt.Printf("Problems fixed: %d\n", fixable)
+	return nil
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/alexstory/kanga/data"
+)
+
+// resolveDeck returns the deck entries of profile are logged with and the
+// deck stats are restricted to, from the --deck flag, falling back on the
+// deck in use by the profile when logging. Without a store, as for seed, the
+// flag is taken as is.
+func resolveDeck(ctx context.Context, store data.Store, command, profile, flagValue string) (deck, filter string, err error) {
+	switch flagValue {
+	case "":
+		if store == nil || profile == "" {
+			return "", "", nil
+		}
+		active, err := store.ActiveDeck(ctx, profile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get the active deck: %w", err)
+		}
+		return active, "", nil
+	case data.NoDeck:
+		return "", data.NoDeck, nil
+	}
+
+	name, err := data.NormalizeDeck(flagValue)
+	if err != nil {
+		return "", "", usageError(command, "--deck: %v", err)
+	}
+	if store != nil {
+		decks, err := store.Decks(ctx)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get decks: %w", err)
+		}
+		if !slices.Contains(decks, name) {
+			return "", "", fmt.Errorf("%w: no deck %q (see `kanga deck list`)", data.ErrNotFound, name)
+		}
+	}
+	return name, name, nil
+}
+
+// Deck adds and switches decks, and compares their damage.
+func Deck(ctx context.Context, store data.Store, inv *Invocation, args []string, filter data.Filter) error {
+	if len(args) < 1 {
+		PrintHelp("deck")
+		return usageError("deck", "missing argument")
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) != 2 {
+			return usageError("deck", "deck add takes a deck name")
+		}
+		name, err := data.NormalizeDeck(args[1])
+		if err != nil {
+			return err
+		}
+		if err := store.AddDeck(ctx, name); err != nil {
+			return err
+		}
+		fmt.Printf("Deck %s added\n", name)
+		return nil
+	case "use":
+		if len(args) != 2 {
+			return usageError("deck", "deck use takes a deck name, or none")
+		}
+		if err := inv.requireProfile("deck"); err != nil {
+			return err
+		}
+		if args[1] == data.NoDeck {
+			if err := store.UseDeck(ctx, inv.Profile, data.NoDeck); err != nil {
+				return err
+			}
+			fmt.Printf("%s now logs entries without a deck\n", inv.Profile)
+			return nil
+		}
+		name, err := data.NormalizeDeck(args[1])
+		if err != nil {
+			return err
+		}
+		if err := store.UseDeck(ctx, inv.Profile, name); err != nil {
+			return err
+		}
+		fmt.Printf("%s now logs entries with %s\n", inv.Profile, name)
+		return nil
+	case "list":
+		return ListDecks(ctx, store, filter, inv.Deck)
+	}
+	return usageError("deck", "invalid argument %q for deck command", args[0])
+}
+
+// ListDecks compares the coin-flip damage of every deck over the entries
+// matching filter, marking the deck in use. Entries logged without a deck
+// get a row of their own when there are any. active is the deck in use.
+func ListDecks(ctx context.Context, store data.Store, filter data.Filter, active string) error {
+	decks, err := store.Decks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get decks: %w", err)
+	}
+	if filter.Deck != "" {
+		decks = []string{filter.Deck}
+	} else {
+		decks = append(decks, data.NoDeck)
+	}
+
+	var rows [][]string
+	for _, name := range decks {
+		filter.Deck = name
+		sel, err := selectStats(ctx, store, filter)
+		if err != nil {
+			return err
+		}
+		attacks := sel.flips.TotalFlips / 2
+		if name == data.NoDeck {
+			if attacks+sel.egg.TotalEntries+sel.misty.TotalEntries == 0 {
+				continue
+			}
+			name = "(no deck)"
+		} else if name == active {
+			name += " (active)"
+		}
+
+		var kangaDamage, eggDamage, mistyEnergy *float64
+		if attacks > 0 {
+			v := float64(sel.flips.TotalHeads*data.KangaDamagePerHeads) / float64(attacks)
+			kangaDamage = &v
+		}
+		if sel.egg.TotalEntries > 0 {
+			v := float64(sel.egg.TotalHeads*data.EggHeadsDamage+sel.egg.TotalTails*data.EggTailsDamage) / float64(sel.egg.TotalEntries)
+			eggDamage = &v
+		}
+		if sel.misty.TotalEntries > 0 {
+			v := float64(sel.misty.TotalHeads) / float64(sel.misty.TotalEntries)
+			mistyEnergy = &v
+		}
+		rows = append(rows, []string{name,
+			fmt.Sprintf("%d", attacks), formatAverage(kangaDamage, "%.1f"),
+			fmt.Sprintf("%d", sel.egg.TotalEntries), formatAverage(eggDamage, "%.1f"),
+			fmt.Sprintf("%d", sel.misty.TotalEntries), formatAverage(mistyEnergy, "%.2f")})
+	}
+	if len(rows) == 0 {
+		rows = append(rows, []string{"No decks", "", "", "", "", "", ""})
+	}
+	printGrid("DECKS", []string{"Deck", "Kanga attacks", "Kanga damage", "Egg attacks", "Egg damage", "Misty attempts", "Misty energy"}, rows)
+	return nil
+}
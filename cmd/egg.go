@@ -1,30 +1,28 @@
 package cmd
 
 import (
-	"database/sql"
-	"flag"
+	"context"
 	"fmt"
 
 	"github.com/alexstory/kanga/data"
 )
 
-func Egg(db *sql.DB) {
-	if flag.NArg() < 2 {
-		fmt.Println("Usage: kanga egg <H|HX|T|TX|stats>")
-		fmt.Println("Log an exeggutor entry or show stats")
-		fmt.Println("  H   - Log a heads")
-		fmt.Println("  T   - Log a tails")
-		fmt.Println("  HX  - Log a heads, but... the result didn't really matter")
-		fmt.Println("  TX  - Log a tails, but... the result didn't really matter")
-		fmt.Println("  stats - Show exeggutor statistics")
-		return
+// Egg logs an exeggutor entry with meta, or shows statistics of the entries
+// carrying meta's tags.
+func Egg(ctx context.Context, store data.Store, inv *Invocation, args []string, meta data.Meta) error {
+	if len(args) < 1 {
+		PrintHelp("egg")
+		return usageError("egg", "missing argument")
 	}
-	arg := flag.Arg(1)
+	arg := args[0]
 	if arg == "stats" {
-		stats, err := data.GetEggStats(db)
+		stats, err := store.GetEggStats(ctx, inv.entryFilter(meta))
 		if err != nil {
-			fmt.Printf("Failed to get egg stats: %v\n", err)
-			return
+			return fmt.Errorf("failed to get egg stats: %w", err)
+		}
+		averageDamage := 0
+		if stats.TotalEntries > 0 {
+			averageDamage = ((stats.TotalHeads * data.EggHeadsDamage) + (stats.TotalTails * data.EggTailsDamage)) / stats.TotalEntries
 		}
 		dataPairs := []LabelValuePair{
 			{"Total flips", fmt.Sprintf("%d", stats.TotalEntries)},
@@ -34,10 +32,10 @@ func Egg(db *sql.DB) {
 			{"Tails percentage", fmt.Sprintf("%.2f%%", percentage(stats.TotalTails, stats.TotalEntries))},
 			{"Heads that mattered", fmt.Sprintf("%d", stats.HeadsMattered)},
 			{"Percent when it mattered", fmt.Sprintf("%.2f%%", percentage(stats.HeadsMattered, stats.TotalEntries-stats.TotalNotMattered))},
-			{"Average damage", fmt.Sprintf("%d", ((stats.TotalHeads*80)+(stats.TotalTails*40))/stats.TotalEntries)},
+			{"Average damage", fmt.Sprintf("%d", averageDamage)},
 		}
 		printTable("EXEGGUTOR STATS", dataPairs)
-		return
+		return nil
 	}
 	var eggType data.EggType
 	switch arg {
@@ -49,15 +47,26 @@ func Egg(db *sql.DB) {
 		eggType = data.T
 	case "TX", "tx":
 		eggType = data.TX
+	case "mattered":
+		return matteredReport(ctx, store, data.Egg, inv.entryFilter(meta))
 	case "undo":
-		data.UndoEgg(db)
+		if err := inv.requireProfile("egg"); err != nil {
+			return err
+		}
+		if err := store.UndoEgg(ctx, inv.Profile); err != nil {
+			return err
+		}
 		fmt.Println("Last exeggutor flip undone...")
-		return
+		return nil
 	default:
-		fmt.Println("Invalid argument for egg command.")
-		fmt.Println("use `kanga help egg` for more info")
-		return
+		return usageError("egg", "invalid argument %q for egg command", arg)
+	}
+	if err := inv.requireProfile("egg"); err != nil {
+		return err
+	}
+	if err := store.InsertExeggutor(ctx, eggType, meta); err != nil {
+		return err
 	}
-	data.InsertExeggutor(db, eggType)
 	fmt.Println("Exeggutor entry logged...")
+	return nil
 }
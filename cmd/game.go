@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/alexstory/kanga/data"
+	"github.com/alexstory/kanga/stats"
+)
+
+// minReliableGames is the number of won and lost games under which the luck
+// regression is flagged as unreliable.
+const minReliableGames = 20
+
+// Game closes the current game with a result, linking it the entries logged
+// since the previous game, or reports how luck relates to game outcomes.
+func Game(ctx context.Context, store data.Store, inv *Invocation, args []string, meta data.Meta, since string) error {
+	if len(args) < 1 {
+		PrintHelp("game")
+		return usageError("game", "missing argument")
+	}
+
+	switch arg := args[0]; arg {
+	case "win", "loss", "draw":
+		if err := inv.requireProfile("game"); err != nil {
+			return err
+		}
+		var start time.Time
+		if since != "" {
+			t, err := parseAt(since, inv.now())
+			if err != nil {
+				return usageError("game", "--since: %v", err)
+			}
+			start = t
+		}
+		game, err := store.EndGame(ctx, data.GameResult(arg), meta, start)
+		if err != nil {
+			return fmt.Errorf("failed to end game: %w", err)
+		}
+		fmt.Printf("Game #%d logged as a %s, with %d flips, %d exeggutor entries and %d misty entries\n",
+			game.ID, game.Result, game.Flips, game.Eggs, game.Misty)
+		return nil
+	case "report":
+		return GameReport(ctx, store, inv.entryFilter(meta))
+	}
+	return usageError("game", "invalid argument %q for game command", args[0])
+}
+
+// gameLuck returns how many standard deviations the heads of a game are above
+// what fair coins give on average, over every card. Kangaskhan expects 1
+// heads per attack (variance 1/2), Exeggutor 1/2 (variance 1/4) and Misty 1
+// heads per attempt (variance 2). It returns NaN for games without flips.
+func gameLuck(g data.GameLuck) float64 {
+	excess := float64(g.FlipHeads-g.Flips) + float64(g.EggHeads) - float64(g.Eggs)/2 + float64(g.MistyHeads-g.Misty)
+	variance := float64(g.Flips)/2 + float64(g.Eggs)/4 + float64(g.Misty)*2
+	if variance == 0 {
+		return math.NaN()
+	}
+	return excess / math.Sqrt(variance)
+}
+
+// GameReport fits a logistic regression of winning on per-game luck, over
+// the games matching filter. Draws are left out of the fit.
+func GameReport(ctx context.Context, store data.Store, filter data.Filter) error {
+	games, err := store.GameLuck(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to get games: %w", err)
+	}
+
+	results := make(map[data.GameResult]int)
+	luckSums := make(map[data.GameResult]float64)
+	luckCounts := make(map[data.GameResult]int)
+	var lucks []float64
+	var wins []bool
+	withFlips := 0
+	for _, g := range games {
+		results[g.Result]++
+		luck := gameLuck(g)
+		if math.IsNaN(luck) {
+			continue
+		}
+		withFlips++
+		luckSums[g.Result] += luck
+		luckCounts[g.Result]++
+		if g.Result != data.Draw {
+			lucks = append(lucks, luck)
+			wins = append(wins, g.Result == data.Win)
+		}
+	}
+	meanLuck := func(result data.GameResult) string {
+		if luckCounts[result] == 0 {
+			return "n/a"
+		}
+		return fmt.Sprintf("%+.2f SD", luckSums[result]/float64(luckCounts[result]))
+	}
+
+	dataPairs := []LabelValuePair{
+		{"Games", fmt.Sprintf("%d", len(games))},
+		{"Wins / losses / draws", fmt.Sprintf("%d / %d / %d", results[data.Win], results[data.Loss], results[data.Draw])},
+		{"Games with flips", fmt.Sprintf("%d", withFlips)},
+		{"Mean luck in wins", meanLuck(data.Win)},
+		{"Mean luck in losses", meanLuck(data.Loss)},
+	}
+
+	intercept, slope, se := stats.LogisticRegression(lucks, wins)
+	if math.IsNaN(slope) {
+		dataPairs = append(dataPairs, LabelValuePair{"Note", "need both wins and losses, with varying luck, to fit"})
+		printTable("LUCK AND GAME OUTCOMES", dataPairs)
+		return nil
+	}
+	lo, hi := stats.OddsRatioCI(slope, se)
+	dataPairs = append(dataPairs,
+		LabelValuePair{"Log-odds per SD of luck", fmt.Sprintf("%+.3f (SE %.3f)", slope, se)},
+		LabelValuePair{"Odds ratio per SD", fmt.Sprintf("%.2f [%.2f, %.2f]", math.Exp(slope), lo, hi)},
+		LabelValuePair{"p-value", formatStat(stats.WaldP(slope, se), "%.4f")},
+		LabelValuePair{"Win rate at -1 SD luck", fmt.Sprintf("%.1f%%", stats.Logistic(intercept-slope)*100)},
+		LabelValuePair{"Win rate at 0 SD luck", fmt.Sprintf("%.1f%%", stats.Logistic(intercept)*100)},
+		LabelValuePair{"Win rate at +1 SD luck", fmt.Sprintf("%.1f%%", stats.Logistic(intercept+slope)*100)},
+	)
+	if len(lucks) < minReliableGames {
+		dataPairs = append(dataPairs, LabelValuePair{"Note", "too few games for a reliable fit"})
+	}
+	printTable("LUCK AND GAME OUTCOMES", dataPairs)
+	return nil
+}
@@ -1,73 +1,102 @@
 package cmd
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
 
+// PrintHelp prints the help of a command, or the list of commands when
+// command is empty or unknown.
 func PrintHelp(command string) {
-	switch command {
-	case "heads":
-		fmt.Println("Usage: kanga heads")
-		fmt.Println("Show heads info")
-	case "tails":
-		fmt.Println("Usage: kanga tails")
-		fmt.Println("Show tails info")
-	case "stats":
-		fmt.Println("Usage: kanga stats")
-		fmt.Println("Show statistics")
-	case "TT", "tt":
-		fmt.Println("Usage: kanga TT")
-		fmt.Println("Log a double tails flip")
-	case "HH", "hh":
-		fmt.Println("Usage: kanga HH")
-		fmt.Println("Log a double heads flip")
-	case "HT", "ht":
-		fmt.Println("Usage: kanga HT")
-		fmt.Println("Log a heads-tails flip")
-	case "TH", "th":
-		fmt.Println("Usage: kanga TH")
-		fmt.Println("Log a tails-heads flip")
-	case "egg":
-		fmt.Println("Usage: kanga egg <command>")
-		fmt.Println("Log an exeggutor entry or show stats")
-		fmt.Println("  H        Log a heads")
-		fmt.Println("  T        Log a tails")
-		fmt.Println("  HX       Log a heads, but... the result didn't really matter")
-		fmt.Println("  TX       Log a tails, but... the result didn't really matter")
-		fmt.Println("  stats    Show exeggutor statistics")
-		fmt.Println("  undo     Undo the last exeggutor entry")
-	case "misty":
-		fmt.Println("Usage: kanga misty <command>")
-		fmt.Println("Log a misty entry or show stats")
-		fmt.Println("  <number> Log a misty entry with the specified number of heads")
-		fmt.Println("  stats    Show misty statistics")
-		fmt.Println("  undo     Undo the last misty entry")
-	case "reset":
-		fmt.Println("Usage: kanga reset")
-		fmt.Println("Reset the database")
-	case "undo":
-		fmt.Println("Usage: kanga undo")
-		fmt.Println("Undo the last action")
-	case "dump-csv":
-		fmt.Println("Usage: kanga dump-csv [folder]")
-		fmt.Println("Dump the data to CSV files in the specified folder (default: current directory)")
-	case "read-csv":
-		fmt.Println("Usage: kanga read-csv [folder]")
-		fmt.Println("Read the data from CSV files in the specified folder (default: current directory)")
-	default:
-		fmt.Println("Usage: kanga [command]")
-		fmt.Println("Commands:")
-		fmt.Println("  heads       Show heads info")
-		fmt.Println("  tails       Show tails info")
-		fmt.Println("  stats       Show statistics")
-		fmt.Println("  TT, tt      Log a double tails flip")
-		fmt.Println("  HH, hh      Log a double heads flip")
-		fmt.Println("  HT, ht      Log a heads-tails flip")
-		fmt.Println("  TH, th      Log a tails-heads flip")
-		fmt.Println("  egg         Run the exeggutor command")
-		fmt.Println("  misty       Run the misty command")
-		fmt.Println("  reset       Reset the database")
-		fmt.Println("  undo        Undo the last action")
-		fmt.Println("  dump-csv    Dump the data to CSV files")
-		fmt.Println("  read-csv    Read the data from CSV files")
-		fmt.Println("  help        Show this help message, or help for a specific command")
+	c := Lookup(command)
+	if c == nil {
+		printCommandList()
+		return
+	}
+
+	usage := "Usage: kanga " + c.Name
+	if c.Usage != "" {
+		usage += " " + c.Usage
+	}
+	flags := commandFlags(c)
+	if len(flags) > 0 {
+		usage += " [flags]"
+	}
+	fmt.Println(usage)
+	if len(c.Details) > 0 {
+		for _, line := range c.Details {
+			fmt.Println(line)
+		}
+	} else {
+		fmt.Println(c.Summary)
+	}
+	if len(c.Aliases) > 0 {
+		fmt.Println("Aliases: " + strings.Join(c.Aliases, ", "))
+	}
+
+	choices := make([][2]string, len(c.Choices))
+	for i, choice := range c.Choices {
+		choices[i] = [2]string{choice.Name, choice.Help}
+	}
+	printColumns(choices)
+
+	if len(flags) > 0 {
+		fmt.Println("Flags:")
+		printColumns(flags)
+	}
+}
+
+func printCommandList() {
+	fmt.Println("Usage: kanga <command> [arguments] [flags]")
+	fmt.Println("Commands:")
+	rows := make([][2]string, len(commands))
+	for i, c := range commands {
+		rows[i] = [2]string{strings.Join(append([]string{c.Name}, c.Aliases...), ", "), c.Summary}
+	}
+	printColumns(rows)
+
+	fmt.Println("Global flags:")
+	printColumns(flagRows(globalFlagSet()))
+
+	fmt.Println("Exit codes:")
+	fmt.Println("  0  success")
+	fmt.Println("  1  a database operation failed")
+	fmt.Println("  2  bad command line or invalid value")
+	fmt.Println("  3  the database could not be opened")
+	fmt.Println("  4  reading or writing a file failed")
+	fmt.Println("  5  the command timed out (see --timeout) or was interrupted")
+}
+
+// commandFlags returns the help rows of the flags a command declares,
+// leaving out the global flags.
+func commandFlags(c *Command) [][2]string {
+	return flagRows(c.declaredFlags())
+}
+
+func flagRows(fs *flag.FlagSet) [][2]string {
+	var rows [][2]string
+	fs.VisitAll(func(f *flag.Flag) {
+		name, usage := flag.UnquoteUsage(f)
+		label := "--" + f.Name
+		if name != "" {
+			label += " " + name
+		}
+		if f.DefValue != "" && f.DefValue != "0" && f.DefValue != "false" {
+			usage += fmt.Sprintf(" (default: %s)", f.DefValue)
+		}
+		rows = append(rows, [2]string{label, usage})
+	})
+	return rows
+}
+
+// printColumns prints indented rows with their second column aligned.
+func printColumns(rows [][2]string) {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row[0]))
+	}
+	for _, row := range rows {
+		fmt.Printf("  %-*s  %s\n", width, row[0], row[1])
 	}
 }
@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/alexstory/kanga/data"
+)
+
+// historyEntry is an entry of any card, as shown in the history.
+type historyEntry struct {
+	ID        int64
+	Result    string
+	CreatedAt string
+	Meta      data.Meta
+}
+
+// formatEntry formats an entry on a single line.
+func (inv *Invocation) formatEntry(e historyEntry) string {
+	return strings.TrimSpace(fmt.Sprintf("#%d  %s  %s  %s", e.ID, e.Result, inv.localTime(e.CreatedAt), inv.formatMeta(e.Meta)))
+}
+
+// localTime formats a stored UTC timestamp in the time zone of the
+// invocation.
+func (inv *Invocation) localTime(s string) string {
+	t, err := data.ParseTime(s)
+	if err != nil {
+		return s
+	}
+	return t.In(inv.Location).Format(data.TimeLayout)
+}
+
+// History lists the last limit entries of the card given in args, or of
+// every card, keeping those matching filter.
+func History(ctx context.Context, store data.Store, inv *Invocation, args []string, limit int, filter data.Filter) error {
+	cards := []data.TableType{data.Kanga, data.Egg, data.Misty}
+	if len(args) > 0 {
+		card, ok := parseCard(args[0])
+		if !ok {
+			return usageError("history", "invalid card %q", args[0])
+		}
+		cards = []data.TableType{card}
+	}
+
+	for _, card := range cards {
+		entries, err := historyEntries(ctx, store, card, limit, filter)
+		if err != nil {
+			return fmt.Errorf("failed to get history: %w", err)
+		}
+		dataPairs := make([]LabelValuePair, len(entries))
+		for i, e := range entries {
+			dataPairs[i] = LabelValuePair{fmt.Sprintf("#%d  %s", e.ID, e.Result), strings.TrimSpace(inv.localTime(e.CreatedAt) + "  " + inv.formatMeta(e.Meta))}
+		}
+		if len(dataPairs) == 0 {
+			dataPairs = append(dataPairs, LabelValuePair{"No entries", ""})
+		}
+		printTable(cardTitle(card)+" HISTORY", dataPairs)
+	}
+	return nil
+}
+
+func historyEntries(ctx context.Context, store data.Store, card data.TableType, limit int, filter data.Filter) ([]historyEntry, error) {
+	var entries []historyEntry
+	switch card {
+	case data.Kanga:
+		flips, err := store.FlipEntries(ctx, filter, limit)
+		for _, e := range flips {
+			entries = append(entries, historyEntry{e.ID, e.Result(), e.CreatedAt, e.Meta})
+		}
+		return entries, err
+	case data.Egg:
+		eggs, err := store.EggEntries(ctx, filter, limit)
+		for _, e := range eggs {
+			entries = append(entries, historyEntry{e.ID, e.Result(), e.CreatedAt, e.Meta})
+		}
+		return entries, err
+	case data.Misty:
+		misty, err := store.MistyEntries(ctx, filter, limit)
+		for _, e := range misty {
+			entries = append(entries, historyEntry{e.ID, e.Result(), e.CreatedAt, e.Meta})
+		}
+		return entries, err
+	}
+	return nil, fmt.Errorf("unknown card")
+}
+
+func getEntry(ctx context.Context, store data.Store, card data.TableType, id int64) (historyEntry, error) {
+	switch card {
+	case data.Kanga:
+		e, err := store.GetFlip(ctx, id)
+		return historyEntry{e.ID, e.Result(), e.CreatedAt, e.Meta}, err
+	case data.Egg:
+		e, err := store.GetEgg(ctx, id)
+		return historyEntry{e.ID, e.Result(), e.CreatedAt, e.Meta}, err
+	case data.Misty:
+		e, err := store.GetMisty(ctx, id)
+		return historyEntry{e.ID, e.Result(), e.CreatedAt, e.Meta}, err
+	}
+	return historyEntry{}, fmt.Errorf("unknown card")
+}
+
+// profileEntry returns the entry edit and delete work on, failing when it
+// belongs to another profile than the one in use, unless with --profile all.
+func (inv *Invocation) profileEntry(ctx context.Context, store data.Store, card data.TableType, id int64) (historyEntry, error) {
+	e, err := getEntry(ctx, store, card, id)
+	if err != nil {
+		return historyEntry{}, err
+	}
+	if inv.Profile != "" && e.Meta.Profile != inv.Profile {
+		return historyEntry{}, fmt.Errorf("%w: %d, logged for profile %s (use --profile %s or --profile all)", data.ErrNotFound, id, e.Meta.Profile, e.Meta.Profile)
+	}
+	return e, nil
+}
+
+// parseEntryArgs parses the "<card> <id>" arguments shared by edit and
+// delete.
+func parseEntryArgs(command string, args []string) (data.TableType, int64, error) {
+	if len(args) < 2 {
+		return 0, 0, usageError(command, "missing card or id")
+	}
+	card, ok := parseCard(args[0])
+	if !ok {
+		return 0, 0, usageError(command, "invalid card %q", args[0])
+	}
+	id, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return 0, 0, usageError(command, "invalid id %q", args[1])
+	}
+	return card, id, nil
+}
+
+// Edit changes the result of an entry, printing it before and after. With
+// dryRun, only the preview is printed.
+func Edit(ctx context.Context, store data.Store, inv *Invocation, args []string, dryRun bool) error {
+	card, id, err := parseEntryArgs("edit", args)
+	if err != nil {
+		return err
+	}
+	if len(args) < 3 {
+		return usageError("edit", "missing new value")
+	}
+	value := args[2]
+
+	before, err := inv.profileEntry(ctx, store, card, id)
+	if err != nil {
+		return err
+	}
+
+	after := before
+	var update func() error
+	switch card {
+	case data.Kanga:
+		flipType, ok := parseFlip(value)
+		if !ok {
+			return usageError("edit", "invalid flip %q", value)
+		}
+		after.Result = strings.ToUpper(value)
+		update = func() error { return store.UpdateFlip(ctx, id, flipType) }
+	case data.Egg:
+		eggs, err := parseEggSequence(value)
+		if err != nil || len(eggs) != 1 {
+			return usageError("edit", "invalid exeggutor result %q", value)
+		}
+		after.Result = strings.ToUpper(value)
+		update = func() error { return store.UpdateEgg(ctx, id, eggs[0]) }
+	case data.Misty:
+		heads, err := strconv.Atoi(value)
+		if err != nil || heads < 0 {
+			return usageError("edit", "invalid misty heads %q", value)
+		}
+		after.Result = strconv.Itoa(heads)
+		update = func() error { return store.UpdateMisty(ctx, id, heads) }
+	}
+
+	fmt.Printf("Before: %s\n", inv.formatEntry(before))
+	fmt.Printf("After:  %s\n", inv.formatEntry(after))
+	if dryRun {
+		fmt.Println("Dry run, nothing changed")
+		return nil
+	}
+	if err := update(); err != nil {
+		return err
+	}
+	fmt.Println("Entry updated...")
+	return nil
+}
+
+// Delete removes an entry, printing it first. With dryRun, only the preview
+// is printed.
+func Delete(ctx context.Context, store data.Store, inv *Invocation, args []string, dryRun bool) error {
+	card, id, err := parseEntryArgs("delete", args)
+	if err != nil {
+		return err
+	}
+
+	before, err := inv.profileEntry(ctx, store, card, id)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Before: %s\n", inv.formatEntry(before))
+	fmt.Printf("After:  (deleted)\n")
+	if dryRun {
+		fmt.Println("Dry run, nothing changed")
+		return nil
+	}
+	if err := store.Delete(ctx, card, id); err != nil {
+		return err
+	}
+	fmt.Println("Entry deleted...")
+	return nil
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alexstory/kanga/data"
+)
+
+func TestEditDeleteProfile(t *testing.T) {
+	ctx := context.Background()
+	store := data.NewMemoryStore()
+	if err := store.AddProfile(ctx, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.InsertFlip(ctx, data.TT, data.Meta{Profile: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	alice := &Invocation{Profile: "alice", Location: time.UTC}
+	bob := &Invocation{Profile: "bob", Location: time.UTC}
+	all := &Invocation{Location: time.UTC}
+
+	if err := Edit(ctx, store, alice, []string{"kanga", "1", "HH"}, false); !errors.Is(err, data.ErrNotFound) {
+		t.Errorf("Edit of another profile's entry = %v, want ErrNotFound", err)
+	}
+	if err := Delete(ctx, store, alice, []string{"kanga", "1"}, false); !errors.Is(err, data.ErrNotFound) {
+		t.Errorf("Delete of another profile's entry = %v, want ErrNotFound", err)
+	}
+	if e, err := store.GetFlip(ctx, 1); err != nil || e.Result() != "TT" {
+		t.Errorf("GetFlip(1) = %+v, %v, want the untouched TT flip", e, err)
+	}
+
+	if err := Edit(ctx, store, bob, []string{"kanga", "1", "HH"}, false); err != nil {
+		t.Errorf("Edit of the profile's own entry: %v", err)
+	}
+	if e, err := store.GetFlip(ctx, 1); err != nil || e.Result() != "HH" {
+		t.Errorf("GetFlip(1) after Edit = %+v, %v, want HH", e, err)
+	}
+	// --profile all reaches the entries of every profile
+	if err := Delete(ctx, store, all, []string{"kanga", "1"}, false); err != nil {
+		t.Errorf("Delete with --profile all: %v", err)
+	}
+	if _, err := store.GetFlip(ctx, 1); !errors.Is(err, data.ErrNotFound) {
+		t.Errorf("GetFlip(1) after Delete = %v, want ErrNotFound", err)
+	}
+}
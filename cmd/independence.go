@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/alexstory/kanga/data"
+	"github.com/alexstory/kanga/stats"
+)
+
+// Independence checks whether Kangaskhan's coins are independent, both
+// between the two coins of an attack and across consecutive attacks. Rows
+// with coins other than 0 or 1 are skipped and counted.
+func Independence(ctx context.Context, store data.Store, filter data.Filter) error {
+	flips, err := store.FlipEntries(ctx, filter, 0)
+	if err != nil {
+		return fmt.Errorf("failed to get flips: %w", err)
+	}
+
+	// table[i][j] counts attacks where the first coin was i and the second j,
+	// with heads as index 0 so the table reads HH, HT / TH, TT.
+	var table [2][2]int
+	coins := make([]float64, 0, len(flips)*2)
+	attacks := make([]float64, 0, len(flips))
+	skipped := 0
+	for _, f := range flips {
+		if !isCoin(f.Heads1) || !isCoin(f.Heads2) {
+			skipped++
+			continue
+		}
+		table[1-f.Heads1][1-f.Heads2]++
+		coins = append(coins, float64(f.Heads1), float64(f.Heads2))
+		attacks = append(attacks, float64(f.Heads1+f.Heads2))
+	}
+	hh, ht, th, tt := table[0][0], table[0][1], table[1][0], table[1][1]
+
+	dataPairs := []LabelValuePair{
+		{"Attacks", fmt.Sprintf("%d", len(attacks))},
+		{"First H, second H", fmt.Sprintf("%d", hh)},
+		{"First H, second T", fmt.Sprintf("%d", ht)},
+		{"First T, second H", fmt.Sprintf("%d", th)},
+		{"First T, second T", fmt.Sprintf("%d", tt)},
+	}
+	chi2, p := stats.ChiSquare2x2(hh, ht, th, tt)
+	dataPairs = append(dataPairs,
+		LabelValuePair{"Chi-square (df 1)", formatStat(chi2, "%.3f")},
+		LabelValuePair{"Independence p-value", formatStat(p, "%.4f")},
+		LabelValuePair{"Correlation (phi)", formatStat(stats.Phi(hh, ht, th, tt), "%+.4f")},
+	)
+
+	coinR := stats.Autocorrelation(coins, 1)
+	attackR := stats.Autocorrelation(attacks, 1)
+	dataPairs = append(dataPairs,
+		LabelValuePair{"Coin lag-1 autocorr", formatStat(coinR, "%+.4f")},
+		LabelValuePair{"Coin lag-1 p-value", formatStat(stats.AutocorrelationP(coinR, len(coins)), "%.4f")},
+		LabelValuePair{"Attack lag-1 autocorr", formatStat(attackR, "%+.4f")},
+		LabelValuePair{"Attack lag-1 p-value", formatStat(stats.AutocorrelationP(attackR, len(attacks)), "%.4f")},
+	)
+	if skipped > 0 {
+		dataPairs = append(dataPairs, LabelValuePair{"Skipped invalid rows", fmt.Sprintf("%d (see `kanga db check`)", skipped)})
+	}
+	printTable("COIN INDEPENDENCE", dataPairs)
+	return nil
+}
+
+// isCoin reports whether a stored coin is a valid heads (1) or tails (0).
+func isCoin(heads int) bool {
+	return heads == 0 || heads == 1
+}
+
+// formatStat formats a statistic, printing "n/a" when it is undefined
+// because there isn't enough data.
+func formatStat(v float64, format string) string {
+	if math.IsNaN(v) {
+		return "n/a"
+	}
+	return fmt.Sprintf(format, v)
+}
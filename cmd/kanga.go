@@ -1,17 +1,16 @@
 package cmd
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 
 	"github.com/alexstory/kanga/data"
 )
 
-func Heads(db *sql.DB) {
-	totalFlips, headsCount, err := data.HeadsInfo(db)
+func Heads(ctx context.Context, store data.Store, filter data.Filter) error {
+	totalFlips, headsCount, err := store.HeadsInfo(ctx, filter)
 	if err != nil {
-		fmt.Printf("Failed to get heads info: %v\n", err)
-		return
+		return fmt.Errorf("failed to get heads info: %w", err)
 	}
 	dataPairs := []LabelValuePair{
 		{"Total flips", fmt.Sprintf("%d", totalFlips)},
@@ -19,13 +18,13 @@ func Heads(db *sql.DB) {
 		{"Heads percentage", fmt.Sprintf("%.2f%%", percentage(headsCount, totalFlips))},
 	}
 	printTable("HEADS INFO", dataPairs)
+	return nil
 }
 
-func Tails(db *sql.DB) {
-	totalFlips, tailsCount, err := data.TailsInfo(db)
+func Tails(ctx context.Context, store data.Store, filter data.Filter) error {
+	totalFlips, tailsCount, err := store.TailsInfo(ctx, filter)
 	if err != nil {
-		fmt.Printf("Failed to get tails info: %v\n", err)
-		return
+		return fmt.Errorf("failed to get tails info: %w", err)
 	}
 	dataPairs := []LabelValuePair{
 		{"Total flips", fmt.Sprintf("%d", totalFlips)},
@@ -33,13 +32,13 @@ func Tails(db *sql.DB) {
 		{"Tails percentage", fmt.Sprintf("%.2f%%", percentage(tailsCount, totalFlips))},
 	}
 	printTable("TAILS INFO", dataPairs)
+	return nil
 }
 
-func Stats(db *sql.DB) {
-	stats, err := data.Flips(db)
+func Stats(ctx context.Context, store data.Store, filter data.Filter) error {
+	stats, err := store.Flips(ctx, filter)
 	if err != nil {
-		fmt.Printf("Failed to get stats: %v\n", err)
-		return
+		return fmt.Errorf("failed to get stats: %w", err)
 	}
 	dataPairs := []LabelValuePair{
 		{"Total flips", fmt.Sprintf("%d", stats.TotalFlips)},
@@ -53,4 +52,18 @@ func Stats(db *sql.DB) {
 		{"Double tails percentage", fmt.Sprintf("%.2f%%", percentage(stats.DoubleTails, stats.TotalFlips))},
 	}
 	printTable("STATISTICS", dataPairs)
+	return nil
+}
+
+// InsertFlip logs a Kangaskhan attack.
+func InsertFlip(ctx context.Context, store data.Store, flipType data.FlipType, meta data.Meta) error {
+	if err := store.InsertFlip(ctx, flipType, meta); err != nil {
+		return err
+	}
+	if flipType == data.TT {
+		fmt.Printf("flip logged... RIP\n")
+	} else {
+		fmt.Printf("flip logged...\n")
+	}
+	return nil
 }
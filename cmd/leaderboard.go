@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/alexstory/kanga/data"
+)
+
+// leaderboardEntry is the row of a profile on the leaderboard. Averages are
+// nil when the profile has no entries for the card.
+type leaderboardEntry struct {
+	Rank               int      `json:"rank"`
+	Profile            string   `json:"profile"`
+	Coins              int      `json:"coins"`
+	Heads              int      `json:"heads"`
+	HeadsRate          float64  `json:"heads_rate"`
+	AdjustedHeadsRate  float64  `json:"adjusted_heads_rate"`
+	MistyAttempts      int      `json:"misty_attempts"`
+	MistyEnergy        *float64 `json:"misty_average_energy"`
+	EggAttacks         int      `json:"egg_attacks"`
+	EggDamage          *float64 `json:"egg_damage_per_attack"`
+	LongestTailsStreak int      `json:"longest_tails_streak"`
+}
+
+// leaderboardSorts are the values accepted by --sort, with the value each
+// sorts by, highest first.
+var leaderboardSorts = map[string]func(e leaderboardEntry) *float64{
+	"luck":   func(e leaderboardEntry) *float64 { return &e.AdjustedHeadsRate },
+	"misty":  func(e leaderboardEntry) *float64 { return e.MistyEnergy },
+	"egg":    func(e leaderboardEntry) *float64 { return e.EggDamage },
+	"streak": func(e leaderboardEntry) *float64 { v := float64(e.LongestTailsStreak); return &v },
+}
+
+// Leaderboard ranks every profile with entries by luck. The heads rate of
+// every coin flipped is pulled toward a fair coin by prior coins, so small
+// samples don't top the board.
+func Leaderboard(ctx context.Context, store data.Store, sortBy string, prior int, format string, filter data.Filter) error {
+	key, ok := leaderboardSorts[sortBy]
+	if !ok {
+		return usageError("leaderboard", "invalid value %q for --sort: must be luck, misty, egg or streak", sortBy)
+	}
+	if prior < 0 {
+		return usageError("leaderboard", "--prior must not be negative")
+	}
+	if err := checkFormat("leaderboard", format); err != nil {
+		return err
+	}
+
+	profiles, err := store.Profiles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get profiles: %w", err)
+	}
+	entries := []leaderboardEntry{}
+	for _, profile := range profiles {
+		filter.Profile = profile
+		e, err := leaderboardStats(ctx, store, filter, prior)
+		if err != nil {
+			return err
+		}
+		if e.Coins > 0 {
+			e.Profile = profile
+			entries = append(entries, e)
+		}
+	}
+
+	slices.SortStableFunc(entries, func(a, b leaderboardEntry) int {
+		ka, kb := key(a), key(b)
+		switch {
+		case ka == nil && kb == nil:
+			return 0
+		case ka == nil:
+			return 1
+		case kb == nil:
+			return -1
+		}
+		return cmp.Compare(*kb, *ka)
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+
+	switch format {
+	case "json":
+		return writeJSON(entries)
+	case "csv":
+		header := []string{"rank", "profile", "coins", "heads", "heads_rate", "adjusted_heads_rate",
+			"misty_attempts", "misty_average_energy", "egg_attacks", "egg_damage_per_attack", "longest_tails_streak"}
+		rows := make([][]string, len(entries))
+		for i, e := range entries {
+			rows[i] = []string{strconv.Itoa(e.Rank), e.Profile, strconv.Itoa(e.Coins), strconv.Itoa(e.Heads),
+				csvFloat(&e.HeadsRate), csvFloat(&e.AdjustedHeadsRate), strconv.Itoa(e.MistyAttempts),
+				csvFloat(e.MistyEnergy), strconv.Itoa(e.EggAttacks), csvFloat(e.EggDamage), strconv.Itoa(e.LongestTailsStreak)}
+		}
+		return writeCSV(header, rows)
+	}
+
+	rows := make([][]string, len(entries))
+	for i, e := range entries {
+		rows[i] = []string{fmt.Sprintf("%d. %s", e.Rank, e.Profile), fmt.Sprintf("%d", e.Coins),
+			fmt.Sprintf("%.2f%%", e.HeadsRate*100), fmt.Sprintf("%.2f%%", e.AdjustedHeadsRate*100),
+			formatAverage(e.MistyEnergy, "%.2f"), formatAverage(e.EggDamage, "%.1f"), fmt.Sprintf("%d", e.LongestTailsStreak)}
+	}
+	if len(rows) == 0 {
+		rows = append(rows, []string{"No entries", "", "", "", "", "", ""})
+	}
+	printGrid("LEADERBOARD", []string{"Profile", "Coins", "Heads", "Adjusted", "Misty energy", "Egg damage", "Tails streak"}, rows)
+	return nil
+}
+
+// leaderboardStats computes the leaderboard entry of the entries matching
+// filter.
+func leaderboardStats(ctx context.Context, store data.Store, filter data.Filter, prior int) (leaderboardEntry, error) {
+	var e leaderboardEntry
+	sel, err := selectStats(ctx, store, filter)
+	if err != nil {
+		return e, err
+	}
+	e.Heads, e.Coins = sel.coins()
+	if e.Coins > 0 {
+		e.HeadsRate = float64(e.Heads) / float64(e.Coins)
+	}
+	// Posterior mean of the heads rate under a Beta(prior/2, prior/2) prior
+	e.AdjustedHeadsRate = (float64(e.Heads) + float64(prior)/2) / float64(e.Coins+prior)
+
+	e.MistyAttempts = sel.misty.TotalEntries
+	if e.MistyAttempts > 0 {
+		energy := float64(sel.misty.TotalHeads) / float64(e.MistyAttempts)
+		e.MistyEnergy = &energy
+	}
+	e.EggAttacks = sel.egg.TotalEntries
+	if e.EggAttacks > 0 {
+		damage := float64(sel.egg.TotalHeads*data.EggHeadsDamage+sel.egg.TotalTails*data.EggTailsDamage) / float64(e.EggAttacks)
+		e.EggDamage = &damage
+	}
+
+	e.LongestTailsStreak, err = longestTailsStreak(ctx, store, filter)
+	return e, err
+}
+
+// longestTailsStreak returns the longest run of tails over every coin
+// flipped, by any card, in the order they were logged.
+func longestTailsStreak(ctx context.Context, store data.Store, filter data.Filter) (int, error) {
+	type attack struct {
+		createdAt string
+		coins     []int
+	}
+	var attacks []attack
+	flips, err := store.FlipEntries(ctx, filter, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get flips: %w", err)
+	}
+	for _, f := range flips {
+		attacks = append(attacks, attack{f.CreatedAt, []int{f.Heads1, f.Heads2}})
+	}
+	eggs, err := store.EggEntries(ctx, filter, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get egg entries: %w", err)
+	}
+	for _, e := range eggs {
+		attacks = append(attacks, attack{e.CreatedAt, []int{e.Heads}})
+	}
+	misty, err := store.MistyEntries(ctx, filter, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get misty entries: %w", err)
+	}
+	for _, m := range misty {
+		// Misty flips until tails
+		coins := make([]int, m.Heads+1)
+		for i := range m.Heads {
+			coins[i] = 1
+		}
+		attacks = append(attacks, attack{m.CreatedAt, coins})
+	}
+
+	// Timestamps are stored in UTC RFC 3339, so they sort as strings
+	slices.SortStableFunc(attacks, func(a, b attack) int { return cmp.Compare(a.createdAt, b.createdAt) })
+	longest, run := 0, 0
+	for _, a := range attacks {
+		for _, heads := range a.coins {
+			if heads == 1 {
+				run = 0
+				continue
+			}
+			run++
+			longest = max(longest, run)
+		}
+	}
+	return longest, nil
+}
+
+// formatAverage formats an average for the table, or "n/a" when it is nil.
+func formatAverage(v *float64, format string) string {
+	if v == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf(format, *v)
+}
+
+// csvFloat formats a value for CSV output, leaving it empty when it is nil.
+func csvFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
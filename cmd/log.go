@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/alexstory/kanga/data"
+)
+
+// Log inserts many results at once, from args or, when args is empty or
+// "-", from stdin, all with the same meta. Everything is validated before a
+// single transaction writes it.
+func Log(ctx context.Context, store data.Store, args []string, meta data.Meta) error {
+	var batch data.Batch
+	var err error
+	if len(args) == 0 || (len(args) == 1 && args[0] == "-") {
+		batch, err = parseLogReader(os.Stdin)
+	} else {
+		batch, err = parseLogLine(args, data.Kanga)
+	}
+	if err != nil {
+		return err
+	}
+	if batch.Len() == 0 {
+		return usageError("log", "nothing to log")
+	}
+	batch.Meta = meta
+
+	if err := store.InsertBatch(ctx, batch); err != nil {
+		return err
+	}
+	fmt.Printf("Logged %d flips, %d exeggutor entries and %d misty entries\n",
+		len(batch.Flips), len(batch.Eggs), len(batch.Misty))
+	return nil
+}
+
+// parseLogReader parses every line of r. Each line starts out logging
+// Kangaskhan flips.
+func parseLogReader(r io.Reader) (data.Batch, error) {
+	var batch data.Batch
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		b, err := parseLogLine(strings.Fields(scanner.Text()), data.Kanga)
+		if err != nil {
+			return data.Batch{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		batch.Flips = append(batch.Flips, b.Flips...)
+		batch.Eggs = append(batch.Eggs, b.Eggs...)
+		batch.Misty = append(batch.Misty, b.Misty...)
+	}
+	return batch, scanner.Err()
+}
+
+// parseLogLine parses log tokens. A card name switches which card the
+// following tokens are logged for.
+func parseLogLine(tokens []string, card data.TableType) (data.Batch, error) {
+	var batch data.Batch
+	for _, token := range tokens {
+		if c, ok := parseCard(token); ok {
+			card = c
+			continue
+		}
+		switch card {
+		case data.Kanga:
+			flipType, ok := parseFlip(token)
+			if !ok {
+				return data.Batch{}, usageError("log", "invalid flip %q", token)
+			}
+			batch.Flips = append(batch.Flips, flipType)
+		case data.Egg:
+			eggs, err := parseEggSequence(token)
+			if err != nil {
+				return data.Batch{}, err
+			}
+			batch.Eggs = append(batch.Eggs, eggs...)
+		case data.Misty:
+			heads, err := strconv.Atoi(token)
+			if err != nil || heads < 0 {
+				return data.Batch{}, usageError("log", "invalid misty heads %q", token)
+			}
+			batch.Misty = append(batch.Misty, heads)
+		}
+	}
+	return batch, nil
+}
+
+func parseFlip(token string) (data.FlipType, bool) {
+	switch strings.ToUpper(token) {
+	case "TT":
+		return data.TT, true
+	case "HH":
+		return data.HH, true
+	case "HT":
+		return data.HT, true
+	case "TH":
+		return data.TH, true
+	}
+	return 0, false
+}
+
+// parseEggSequence parses exeggutor results written back to back, where an
+// X marks the previous coin as not mattering: "HHXT" is H, HX, T.
+func parseEggSequence(token string) ([]data.EggType, error) {
+	var eggs []data.EggType
+	s := strings.ToUpper(token)
+	for i := 0; i < len(s); i++ {
+		notMattered := i+1 < len(s) && s[i+1] == 'X'
+		switch {
+		case s[i] == 'H' && notMattered:
+			eggs = append(eggs, data.HX)
+		case s[i] == 'H':
+			eggs = append(eggs, data.H)
+		case s[i] == 'T' && notMattered:
+			eggs = append(eggs, data.TX)
+		case s[i] == 'T':
+			eggs = append(eggs, data.T)
+		default:
+			return nil, usageError("log", "invalid exeggutor result %q in %q", s[i:i+1], token)
+		}
+		if notMattered {
+			i++
+		}
+	}
+	return eggs, nil
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexstory/kanga/data"
+)
+
+// Matchups shows, for every opponent archetype, the Kangaskhan, Exeggutor
+// and Misty attacks made against it with their average damage or energy,
+// and the record and win rate of the games played against it when games are
+// logged.
+func Matchups(ctx context.Context, store data.Store, filter data.Filter) error {
+	matchups, err := store.Matchups(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to get matchups: %w", err)
+	}
+	withGames := false
+	for _, m := range matchups {
+		withGames = withGames || m.Games() > 0
+	}
+
+	header := []string{"Opponent", "Kanga attacks", "Kanga damage", "Egg attacks", "Egg damage", "Misty attempts", "Misty energy"}
+	if withGames {
+		header = append(header, "Games", "Record", "Win rate")
+	}
+	rows := make([][]string, 0, len(matchups))
+	for _, m := range matchups {
+		name := m.Opponent
+		if name == "" {
+			name = "(unknown)"
+		}
+		var kangaDamage, eggDamage, mistyEnergy *float64
+		if m.Flips > 0 {
+			v := float64(m.FlipHeads*data.KangaDamagePerHeads) / float64(m.Flips)
+			kangaDamage = &v
+		}
+		if m.Eggs > 0 {
+			v := float64(m.EggHeads*data.EggHeadsDamage+(m.Eggs-m.EggHeads)*data.EggTailsDamage) / float64(m.Eggs)
+			eggDamage = &v
+		}
+		if m.Misty > 0 {
+			v := float64(m.MistyHeads) / float64(m.Misty)
+			mistyEnergy = &v
+		}
+		row := []string{name,
+			fmt.Sprintf("%d", m.Flips), formatAverage(kangaDamage, "%.1f"),
+			fmt.Sprintf("%d", m.Eggs), formatAverage(eggDamage, "%.1f"),
+			fmt.Sprintf("%d", m.Misty), formatAverage(mistyEnergy, "%.2f")}
+		if withGames {
+			var winRate *float64
+			if m.Games() > 0 {
+				v := float64(m.Wins) / float64(m.Games()) * 100
+				winRate = &v
+			}
+			row = append(row, fmt.Sprintf("%d", m.Games()),
+				fmt.Sprintf("%d-%d-%d", m.Wins, m.Losses, m.Draws), formatAverage(winRate, "%.1f%%"))
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		row := make([]string, len(header))
+		row[0] = "No entries"
+		rows = append(rows, row)
+	}
+	printGrid("MATCHUPS", header, rows)
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alexstory/kanga/data"
+	"github.com/alexstory/kanga/stats"
+)
+
+// Mattered compares the heads rate of flips that mattered with the ones that
+// didn't, for the given card or for every card that records it.
+func Mattered(ctx context.Context, store data.Store, args []string, filter data.Filter) error {
+	cards := []data.TableType{data.Kanga, data.Egg, data.Misty}
+	if len(args) > 0 {
+		card, ok := parseCard(args[0])
+		if !ok || !data.HasMattered(card) {
+			return usageError("mattered", "invalid card %q for mattered command", args[0])
+		}
+		cards = []data.TableType{card}
+	}
+
+	for _, card := range cards {
+		if !data.HasMattered(card) {
+			continue
+		}
+		if err := matteredReport(ctx, store, card, filter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matteredReport(ctx context.Context, store data.Store, card data.TableType, filter data.Filter) error {
+	m, err := store.GetMatteredStats(ctx, card, filter)
+	if err != nil {
+		return fmt.Errorf("failed to get mattered stats: %w", err)
+	}
+
+	dataPairs := []LabelValuePair{
+		{"Flips that mattered", fmt.Sprintf("%d", m.Mattered)},
+		{"Heads rate (mattered)", fmt.Sprintf("%.2f%%", percentage(m.HeadsMattered, m.Mattered))},
+		{"Flips that didn't", fmt.Sprintf("%d", m.NotMattered)},
+		{"Heads rate (didn't)", fmt.Sprintf("%.2f%%", percentage(m.HeadsNotMattered, m.NotMattered))},
+	}
+	if m.Mattered > 0 && m.NotMattered > 0 {
+		diff := percentage(m.HeadsMattered, m.Mattered) - percentage(m.HeadsNotMattered, m.NotMattered)
+		lo, hi := stats.DiffCI(m.HeadsMattered, m.Mattered, m.HeadsNotMattered, m.NotMattered)
+		z, p := stats.TwoProportionZ(m.HeadsMattered, m.Mattered, m.HeadsNotMattered, m.NotMattered)
+		fisher := stats.FisherExact(m.HeadsMattered, m.Mattered-m.HeadsMattered,
+			m.HeadsNotMattered, m.NotMattered-m.HeadsNotMattered)
+		dataPairs = append(dataPairs,
+			LabelValuePair{"Difference", fmt.Sprintf("%+.2f%%", diff)},
+			LabelValuePair{"95% CI", fmt.Sprintf("[%+.2f%%, %+.2f%%]", lo*100, hi*100)},
+			LabelValuePair{"Z statistic", fmt.Sprintf("%.3f", z)},
+			LabelValuePair{"Z-test p-value", fmt.Sprintf("%.4f", p)},
+			LabelValuePair{"Fisher exact p-value", fmt.Sprintf("%.4f", fisher)},
+		)
+	} else {
+		dataPairs = append(dataPairs, LabelValuePair{"Note", "need flips on both sides to compare"})
+	}
+	printTable(cardTitle(card)+" WHEN IT MATTERED", dataPairs)
+	return nil
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexstory/kanga/data"
+)
+
+// tagFlag registers a repeatable --tag flag. Each value may hold several
+// comma separated tags.
+func tagFlag(fs *flag.FlagSet, tags *[]string, usage string) {
+	fs.Func("tag", usage, func(s string) error {
+		parsed, err := data.NormalizeTags(strings.Split(s, ","))
+		if err != nil {
+			return err
+		}
+		*tags = append(*tags, parsed...)
+		return nil
+	})
+}
+
+// vsFlag registers --vs, the opponent archetype.
+func vsFlag(fs *flag.FlagSet, opponent *string, usage string) {
+	fs.Func("vs", usage, func(s string) error {
+		name, err := data.NormalizeOpponent(s)
+		if err != nil {
+			return err
+		}
+		*opponent = name
+		return nil
+	})
+}
+
+// atLayouts are the layouts accepted by --at, in the zone of the invocation.
+var atLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// parseAt parses an --at value: a date and time in the time zone of now, an
+// RFC 3339 timestamp, or a time before now such as -90m, -2h or -1d.
+func parseAt(s string, now time.Time) (time.Time, error) {
+	if ago, ok := strings.CutPrefix(s, "-"); ok {
+		d, err := parseSpan(ago)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time %q", s)
+		}
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range atLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+// parseSpan parses a positive duration such as 90m or 2h, or a number of
+// days such as 90d.
+func parseSpan(s string) (time.Duration, error) {
+	var d time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// metaFlags registers --note, --tag, --vs and --at for commands that log
+// entries.
+// The returned function reads them once every flag is parsed, so --at is
+// read in the zone given with --tz wherever it appears.
+func metaFlags(fs *flag.FlagSet) func(inv *Invocation) (data.Meta, error) {
+	readMeta := registerMeta(fs, "Note to attach to the logged entries",
+		"Tag the logged entries (repeatable, comma separated)",
+		"Opponent archetype the entries were played against, e.g. mewtwo")
+	return func(inv *Invocation) (data.Meta, error) {
+		if err := inv.requireProfile(fs.Name()); err != nil {
+			return data.Meta{}, err
+		}
+		return readMeta(inv)
+	}
+}
+
+// entryFlags registers --note, --tag, --vs and --at for commands that both
+// log entries and show statistics. The tags and opponent filter the
+// statistics, and logging has to check requireProfile itself.
+func entryFlags(fs *flag.FlagSet) func(inv *Invocation) (data.Meta, error) {
+	return registerMeta(fs, "Note to attach to the logged entry",
+		"Tag the logged entry, or only count entries with this tag in stats (repeatable, comma separated)",
+		"Opponent archetype the entry was played against, or only count entries against it in stats")
+}
+
+func registerMeta(fs *flag.FlagSet, noteUsage, tagUsage, vsUsage string) func(inv *Invocation) (data.Meta, error) {
+	var meta data.Meta
+	fs.StringVar(&meta.Note, "note", "", noteUsage)
+	tagFlag(fs, &meta.Tags, tagUsage)
+	vsFlag(fs, &meta.Opponent, vsUsage)
+	at := fs.String("at", "", "When the entries happened, e.g. \"2026-10-12 14:30\" or -2h (default: now)")
+	return func(inv *Invocation) (data.Meta, error) {
+		if *at != "" {
+			t, err := parseAt(*at, inv.now())
+			if err != nil {
+				return data.Meta{}, usageError(fs.Name(), "--at: %v", err)
+			}
+			meta.At = t
+		}
+		meta.Profile = inv.Profile
+		meta.Deck = inv.Deck
+		return meta, nil
+	}
+}
+
+// filterFlags registers --tag and --vs for commands that show statistics. The
+// returned function reads the filter once the command runs, scoped to the
+// profile and deck it runs for.
+func filterFlags(fs *flag.FlagSet) func(inv *Invocation) data.Filter {
+	var filter data.Filter
+	tagFlag(fs, &filter.Tags, "Only count entries with this tag (repeatable, comma separated)")
+	vsFlag(fs, &filter.Opponent, "Only count entries played against this opponent archetype")
+	return func(inv *Invocation) data.Filter {
+		filter.Profile = inv.Profile
+		filter.Deck = inv.DeckFilter
+		return filter
+	}
+}
+
+// entryFilter returns the filter of the stats shown by commands using
+// entryFlags.
+func (inv *Invocation) entryFilter(meta data.Meta) data.Filter {
+	return data.Filter{Tags: meta.Tags, Profile: meta.Profile, Deck: inv.DeckFilter, Opponent: meta.Opponent}
+}
+
+// formatMeta formats the deck, opponent, note and tags of an entry for
+// listings, after its profile when entries of every profile are listed.
+func (inv *Invocation) formatMeta(meta data.Meta) string {
+	var parts []string
+	if inv.Profile == "" {
+		parts = append(parts, "@"+meta.Profile)
+	}
+	if meta.Deck != "" && inv.DeckFilter == "" {
+		parts = append(parts, "deck:"+meta.Deck)
+	}
+	if meta.Opponent != "" {
+		parts = append(parts, "vs:"+meta.Opponent)
+	}
+	if len(meta.Tags) > 0 {
+		parts = append(parts, "["+strings.Join(meta.Tags, ", ")+"]")
+	}
+	if meta.Note != "" {
+		parts = append(parts, "\""+meta.Note+"\"")
+	}
+	return strings.Join(parts, " ")
+}
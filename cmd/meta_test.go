@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSpan(t *testing.T) {
+	tests := []struct {
+		s    string
+		want time.Duration
+		ok   bool
+	}{
+		{"90m", 90 * time.Minute, true},
+		{"2h", 2 * time.Hour, true},
+		{"1h30m", 90 * time.Minute, true},
+		{"30d", 30 * 24 * time.Hour, true},
+		{"0d", 0, false},
+		{"0s", 0, false},
+		{"-2h", 0, false},
+		{"d", 0, false},
+		{"1.5d", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseSpan(tt.s)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseSpan(%q) = %v, %v, want %v, ok %v", tt.s, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseAt(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	now := time.Date(2026, 10, 12, 15, 0, 0, 0, paris)
+	tests := []struct {
+		s    string
+		want time.Time
+		ok   bool
+	}{
+		{"-90m", now.Add(-90 * time.Minute), true},
+		{"-1d", now.Add(-24 * time.Hour), true},
+		// Dates and times are read in the zone of now
+		{"2026-10-12 14:30", time.Date(2026, 10, 12, 14, 30, 0, 0, paris), true},
+		{"2026-10-12 14:30:15", time.Date(2026, 10, 12, 14, 30, 15, 0, paris), true},
+		{"2026-10-12T14:30", time.Date(2026, 10, 12, 14, 30, 0, 0, paris), true},
+		{"2026-10-12", time.Date(2026, 10, 12, 0, 0, 0, 0, paris), true},
+		{"2026-10-12T14:30:00Z", time.Date(2026, 10, 12, 14, 30, 0, 0, time.UTC), true},
+		{"-", time.Time{}, false},
+		{"-2x", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+		{"2026-13-01", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parseAt(tt.s, now)
+		if (err == nil) != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseAt(%q) = %v, %v, want %v, ok %v", tt.s, got, err, tt.want, tt.ok)
+		}
+	}
+}
@@ -1,26 +1,37 @@
 package cmd
 
 import (
-	"database/sql"
-	"flag"
+	"context"
 	"fmt"
+	"math"
 	"strconv"
 
 	"github.com/alexstory/kanga/data"
+	kstats "github.com/alexstory/kanga/stats"
 )
 
-func InsertMisty(db *sql.DB, heads int) {
-	data.InsertMisty(db, heads)
+func InsertMisty(ctx context.Context, store data.Store, heads int, meta data.Meta) error {
+	if err := store.InsertMisty(ctx, heads, meta); err != nil {
+		return err
+	}
 	fmt.Printf("Entry logged...\n")
+	return nil
 }
 
-func MistyStats(db *sql.DB) {
+// mistyBuckets is the number of heads buckets Misty's results are shown in,
+// by the geometric fit and the simulation alike: 0, 1, 2 and 3+.
+const mistyBuckets = 4
+
+func MistyStats(ctx context.Context, store data.Store, filter data.Filter) error {
 	var funStat LabelValuePair
 
-	stats, err := data.GetMistyStats(db)
+	stats, err := store.GetMistyStats(ctx, filter)
+	if err != nil {
+		return err
+	}
+	histogram, err := store.GetMistyHistogram(ctx, filter)
 	if err != nil {
-		fmt.Printf("Failed to get misty stats: %v\n", err)
-		return
+		return err
 	}
 
 	if stats.TotalEntries == 0 {
@@ -29,38 +40,90 @@ func MistyStats(db *sql.DB) {
 		funStat = LabelValuePair{"Sins", "infinite"}
 	}
 
+	// Fold the histogram into the fit buckets and compute the counts expected
+	// from a fair coin, where P(k heads) = 0.5^(k+1) and P(3+) = 0.5^3. The
+	// expected counts and the mean come from the histogram's own total, so
+	// that they match the observed counts.
+	observed := make([]int, mistyBuckets)
+	attempts, heads := 0, 0
+	for k, c := range histogram {
+		observed[min(k, mistyBuckets-1)] += c
+		attempts += c
+		heads += k * c
+	}
+	expected := make([]float64, mistyBuckets)
+	for k := range expected {
+		p := math.Pow(0.5, float64(k+1))
+		if k == mistyBuckets-1 {
+			p = math.Pow(0.5, float64(k))
+		}
+		expected[k] = p * float64(attempts)
+	}
+
 	dataPairs := []LabelValuePair{
 		{"Total attempts", fmt.Sprintf("%d", stats.TotalEntries)},
 		{"Total heads", fmt.Sprintf("%d", stats.TotalHeads)},
-		funStat,
+		{"Heads per attempt", "obs / exp"},
 	}
+	for k := range observed {
+		label := fmt.Sprintf("%d heads", k)
+		if k == mistyBuckets-1 {
+			label = fmt.Sprintf("%d+ heads", k)
+		}
+		dataPairs = append(dataPairs, LabelValuePair{label, fmt.Sprintf("%d / %.1f", observed[k], expected[k])})
+	}
+
+	meanEnergy := 0.0
+	if attempts > 0 {
+		meanEnergy = float64(heads) / float64(attempts)
+	}
+	dataPairs = append(dataPairs, LabelValuePair{"Mean energy (exp 1.00)", fmt.Sprintf("%.2f", meanEnergy)})
+
+	if attempts > 0 {
+		chi2, df, p := kstats.ChiSquareFit(observed, expected)
+		dataPairs = append(dataPairs,
+			LabelValuePair{"Chi-square", fmt.Sprintf("%.3f (df %d)", chi2, df)},
+			LabelValuePair{"Fit p-value", fmt.Sprintf("%.4f", p)},
+		)
+		if expected[mistyBuckets-1] < 5 {
+			dataPairs = append(dataPairs, LabelValuePair{"Note", "too few attempts for a reliable fit"})
+		}
+	}
+	dataPairs = append(dataPairs, LabelValuePair{"Record chain", fmt.Sprintf("%d", stats.MaxHeads)}, funStat)
 	printTable("MISTY STATS", dataPairs)
+	return nil
 }
 
-func Misty(db *sql.DB) {
-	if flag.NArg() < 2 {
-		fmt.Println("Usage: kanga misty <command>")
-		fmt.Println("See `kanga help misty` for more info")
-		return
+// Misty logs a misty entry with meta, or shows statistics of the entries
+// carrying meta's tags.
+func Misty(ctx context.Context, store data.Store, inv *Invocation, args []string, meta data.Meta) error {
+	if len(args) < 1 {
+		PrintHelp("misty")
+		return usageError("misty", "missing argument")
 	}
 
-	arg := flag.Arg(1)
+	arg := args[0]
 
 	heads, err := strconv.Atoi(arg)
 	if err == nil {
-		InsertMisty(db, heads)
-		return
+		if err := inv.requireProfile("misty"); err != nil {
+			return err
+		}
+		return InsertMisty(ctx, store, heads, meta)
 	}
 
 	switch arg {
 	case "stats":
-		MistyStats(db)
+		return MistyStats(ctx, store, inv.entryFilter(meta))
 	case "undo":
-		data.UndoMisty(db)
+		if err := inv.requireProfile("misty"); err != nil {
+			return err
+		}
+		if err := store.UndoMisty(ctx, inv.Profile); err != nil {
+			return err
+		}
 		fmt.Println("Last misty flip undone...")
-	default:
-		fmt.Println("Invalid argument for misty command.")
-		fmt.Println("See `kanga help misty` for more info")
+		return nil
 	}
-
+	return usageError("misty", "invalid argument %q for misty command", arg)
 }
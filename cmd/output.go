@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"os"
+	"slices"
+)
+
+// outputFormats are the values accepted by --format. Only the table is meant
+// for people; csv and json are stable for scripts.
+var outputFormats = []string{"table", "csv", "json"}
+
+// formatFlag registers --format for commands that can print their results in
+// a machine-readable format.
+func formatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", "table", "Output format: table, csv or json")
+}
+
+// checkFormat returns a usage error when format is not an output format.
+func checkFormat(command, format string) error {
+	if !slices.Contains(outputFormats, format) {
+		return usageError(command, "invalid value %q for --format: must be table, csv or json", format)
+	}
+	return nil
+}
+
+// writeCSV writes a header and rows of raw values to stdout.
+func writeCSV(header []string, rows [][]string) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// writeJSON writes v to stdout as indented JSON.
+func writeJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
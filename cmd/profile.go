@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/alexstory/kanga/data"
+)
+
+// resolveScope returns the profile a command runs for, from the --profile
+// flag, falling back on the profile in use. It returns "" with
+// --profile all. Without a store, as for seed, the flag is taken as is.
+func resolveScope(ctx context.Context, store data.Store, command, flagValue string) (string, error) {
+	if flagValue == data.AllProfiles {
+		return "", nil
+	}
+	if flagValue == "" {
+		if store == nil {
+			return data.DefaultProfile, nil
+		}
+		active, err := store.ActiveProfile(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get the active profile: %w", err)
+		}
+		return active, nil
+	}
+
+	name, err := data.NormalizeProfile(flagValue)
+	if err != nil {
+		return "", usageError(command, "--profile: %v", err)
+	}
+	if store != nil {
+		profiles, err := store.Profiles(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get profiles: %w", err)
+		}
+		if !slices.Contains(profiles, name) {
+			return "", fmt.Errorf("%w: no profile %q (see `kanga profile list`)", data.ErrNotFound, name)
+		}
+	}
+	return name, nil
+}
+
+// requireProfile fails when entries would be logged or undone with
+// --profile all.
+func (inv *Invocation) requireProfile(command string) error {
+	if inv.Profile == "" {
+		return usageError(command, "--profile all only applies to stats and reset, pick a profile to log or undo entries for")
+	}
+	return nil
+}
+
+// Profile adds, switches and lists player profiles.
+func Profile(ctx context.Context, store data.Store, args []string) error {
+	if len(args) < 1 {
+		PrintHelp("profile")
+		return usageError("profile", "missing argument")
+	}
+
+	switch args[0] {
+	case "add", "use":
+		if len(args) != 2 {
+			return usageError("profile", "profile %s takes a profile name", args[0])
+		}
+		name, err := data.NormalizeProfile(args[1])
+		if err != nil {
+			return err
+		}
+		if args[0] == "add" {
+			if err := store.AddProfile(ctx, name); err != nil {
+				return err
+			}
+			fmt.Printf("Profile %s added\n", name)
+			return nil
+		}
+		if err := store.UseProfile(ctx, name); err != nil {
+			return err
+		}
+		fmt.Printf("Now logging as %s\n", name)
+		return nil
+	case "list":
+		return ListProfiles(ctx, store)
+	}
+	return usageError("profile", "invalid argument %q for profile command", args[0])
+}
+
+// ListProfiles lists every profile with the number of entries logged for
+// it, marking the profile in use.
+func ListProfiles(ctx context.Context, store data.Store) error {
+	profiles, err := store.Profiles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get profiles: %w", err)
+	}
+	active, err := store.ActiveProfile(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the active profile: %w", err)
+	}
+
+	rows := make([][]string, len(profiles))
+	for i, name := range profiles {
+		filter := data.Filter{Profile: name}
+		flips, err := store.Flips(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to get flips: %w", err)
+		}
+		egg, err := store.GetEggStats(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to get egg stats: %w", err)
+		}
+		misty, err := store.GetMistyStats(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to get misty stats: %w", err)
+		}
+		label := name
+		if name == active {
+			label += " (active)"
+		}
+		rows[i] = []string{label, fmt.Sprintf("%d", flips.TotalFlips/2),
+			fmt.Sprintf("%d", egg.TotalEntries), fmt.Sprintf("%d", misty.TotalEntries)}
+	}
+	printGrid("PROFILES", []string{"Profile", "Kanga attacks", "Egg flips", "Misty attempts"}, rows)
+	return nil
+}
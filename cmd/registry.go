@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/alexstory/kanga/data"
+)
+
+// Runner runs a command with its positional arguments, for what the global
+// flags resolved to. Flag values are captured by the Setup function that
+// returned it.
+type Runner func(ctx context.Context, store data.Store, inv *Invocation, args []string) error
+
+// Invocation is what the global flags of a single run resolved to, once the
+// store is open.
+type Invocation struct {
+	// Profile is the profile entries are logged for and stats are computed
+	// over: --profile, or else the active profile. It is empty with
+	// --profile all, where stats cover every profile.
+	Profile string
+	// Deck is the deck entries are logged with: --deck, or else the deck
+	// the profile uses.
+	Deck string
+	// DeckFilter is the deck stats are restricted to with --deck, empty for
+	// every deck and data.NoDeck for entries logged without one.
+	DeckFilter string
+	// Location is the time zone times are shown and read in: --tz, or else
+	// the local one.
+	Location *time.Location
+}
+
+// now returns the current time in the zone of the invocation.
+func (inv *Invocation) now() time.Time {
+	return time.Now().In(inv.Location)
+}
+
+// storeAs returns store as S, or a usage error if it doesn't implement S.
+func storeAs[S data.Store](command string, store data.Store) (S, error) {
+	s, ok := store.(S)
+	if !ok {
+		return s, usageError(command, "%s isn't supported by this store", command)
+	}
+	return s, nil
+}
+
+// Choice is a value accepted as a command's first argument.
+type Choice struct {
+	Name string
+	Help string
+}
+
+// Command describes a kanga subcommand. The registry drives dispatch, help
+// and shell completion, so a command only has to be described once.
+type Command struct {
+	Name    string
+	Aliases []string
+	// Usage describes the positional arguments, e.g. "<card>".
+	Usage   string
+	Summary string
+	// Details are extra help lines printed after the summary.
+	Details []string
+	// Choices lists the values accepted as the first argument. Names in
+	// angle brackets are placeholders, e.g. "<number>".
+	Choices []Choice
+	// NoStore is set for commands that don't touch the database.
+	NoStore bool
+	// NoTimeout is set for commands meant to run long, which only time out
+	// when --timeout is given.
+	NoTimeout bool
+	// Setup registers the command's flags on fs and returns the function
+	// running it. Setup is called once per invocation.
+	Setup func(fs *flag.FlagSet) Runner
+}
+
+// commands holds every command in help order. It is filled in init.
+var commands []*Command
+
+// Commands returns every registered command.
+func Commands() []*Command {
+	return commands
+}
+
+// Lookup returns the command registered under name or one of its aliases.
+func Lookup(name string) *Command {
+	for _, c := range commands {
+		if c.Name == name {
+			return c
+		}
+		for _, alias := range c.Aliases {
+			if alias == name {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// globalFlags holds the flags accepted by every command.
+type globalFlags struct {
+	timeout time.Duration
+	tz      string
+	db      string
+	profile string
+	deck    string
+}
+
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	fs.DurationVar(&g.timeout, "timeout", DefaultTimeout, "Cancel the command after this long (0 disables)")
+	fs.StringVar(&g.tz, "tz", "", "Time zone to show and read times in, e.g. Europe/Paris (default: local)")
+	fs.StringVar(&g.db, "db", "", "Database file to use (default: kanga.db next to the executable)")
+	fs.StringVar(&g.profile, "profile", "", "Profile to log entries for and show stats of, or all (default: the active profile)")
+	fs.StringVar(&g.deck, "deck", "", "Deck to log entries with, or to only count in stats, none for entries without a deck (default: the active deck when logging)")
+}
+
+// declaredFlags returns the command's own flags, without the global ones.
+func (c *Command) declaredFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.Name, flag.ContinueOnError)
+	if c.Setup != nil {
+		c.Setup(fs)
+	}
+	return fs
+}
+
+// globalFlagSet returns a flag set holding only the global flags.
+func globalFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("kanga", flag.ContinueOnError)
+	new(globalFlags).register(fs)
+	return fs
+}
+
+// newFlagSet returns the command's flags along with the global ones.
+func (c *Command) newFlagSet(g *globalFlags) (*flag.FlagSet, Runner) {
+	fs := flag.NewFlagSet(c.Name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	g.register(fs)
+	var run Runner
+	if c.Setup != nil {
+		run = c.Setup(fs)
+	}
+	return fs, run
+}
+
+// parseInterspersed parses flags anywhere in args up to "--" and returns the
+// positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		consumed := len(args) - len(rest)
+		if consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// Execute runs the kanga command line (without the program name) and
+// returns the process exit code.
+func Execute(args []string) int {
+	err := execute(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kanga: %v\n", err)
+	}
+	return ExitCode(err)
+}
+
+// splitCommand returns the command name in args and the other arguments.
+func splitCommand(args []string) (string, []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			rest := append(append([]string{}, args[:i]...), args[i+1:]...)
+			return arg, rest
+		}
+		if !strings.Contains(arg, "=") && flagTakesValue(strings.TrimLeft(arg, "-")) {
+			i++
+		}
+	}
+	return "help", args
+}
+
+// flagTakesValue reports whether any command or global flag named name
+// expects a value.
+func flagTakesValue(name string) bool {
+	f := globalFlagSet().Lookup(name)
+	for _, c := range commands {
+		if f != nil {
+			break
+		}
+		f = c.declaredFlags().Lookup(name)
+	}
+	if f == nil {
+		return false
+	}
+	return !isBoolFlag(f)
+}
+
+// isSet reports whether the flag was given on the command line.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		set = set || f.Name == name
+	})
+	return set
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func execute(args []string) error {
+	name, args := splitCommand(args)
+	c := Lookup(name)
+	if c == nil {
+		PrintHelp("")
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, name)
+	}
+
+	var g globalFlags
+	fs, run := c.newFlagSet(&g)
+	positional, err := parseInterspersed(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		PrintHelp(c.Name)
+		return nil
+	}
+	if err != nil {
+		return usageError(c.Name, "%v", err)
+	}
+	inv := &Invocation{Location: time.Local}
+	if g.tz != "" {
+		if inv.Location, err = time.LoadLocation(g.tz); err != nil {
+			return usageError(c.Name, "invalid time zone %q", g.tz)
+		}
+	}
+
+	if c.NoTimeout && !isSet(fs, "timeout") {
+		g.timeout = 0
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if g.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.timeout)
+		defer cancel()
+	}
+
+	var store data.Store
+	if !c.NoStore {
+		if g.db != "" {
+			store, err = data.Open(ctx, g.db)
+		} else {
+			store, err = data.Init(ctx)
+		}
+		if err != nil {
+			return &storeError{err}
+		}
+		defer store.Close()
+	}
+	if inv.Profile, err = resolveScope(ctx, store, c.Name, g.profile); err != nil {
+		return err
+	}
+	if inv.Deck, inv.DeckFilter, err = resolveDeck(ctx, store, c.Name, inv.Profile, g.deck); err != nil {
+		return err
+	}
+	return run(ctx, store, inv, positional)
+}
+
+// storeError reports that the database could not be opened.
+type storeError struct {
+	err error
+}
+
+func (e *storeError) Error() string {
+	return fmt.Sprintf("failed to initialize database: %v", e.err)
+}
+
+func (e *storeError) Unwrap() error {
+	return e.err
+}
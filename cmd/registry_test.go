@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestParseInterspersed(t *testing.T) {
+	tests := []struct {
+		args       []string
+		positional []string
+		note       string
+		dryRun     bool
+		ok         bool
+	}{
+		{[]string{"egg", "H"}, []string{"egg", "H"}, "", false, true},
+		{[]string{"--note", "x", "egg", "H"}, []string{"egg", "H"}, "x", false, true},
+		{[]string{"egg", "--dry-run", "H", "--note=x"}, []string{"egg", "H"}, "x", true, true},
+		// "--" ends flag parsing, even in the middle of the arguments
+		{[]string{"egg", "--", "-2h..", "--dry-run"}, []string{"egg", "-2h..", "--dry-run"}, "", false, true},
+		{[]string{"--", "--note"}, []string{"--note"}, "", false, true},
+		{[]string{"-"}, []string{"-"}, "", false, true},
+		{nil, nil, "", false, true},
+		{[]string{"egg", "--unknown"}, nil, "", false, false},
+		{[]string{"egg", "--note"}, nil, "", false, false},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		note := fs.String("note", "", "")
+		dryRun := fs.Bool("dry-run", false, "")
+		positional, err := parseInterspersed(fs, tt.args)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseInterspersed(%q) error = %v, want ok %v", tt.args, err, tt.ok)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+		if !reflect.DeepEqual(positional, tt.positional) || *note != tt.note || *dryRun != tt.dryRun {
+			t.Errorf("parseInterspersed(%q) = %q, note %q, dry-run %v, want %q, %q, %v",
+				tt.args, positional, *note, *dryRun, tt.positional, tt.note, tt.dryRun)
+		}
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		name string
+		rest []string
+	}{
+		{[]string{"stats"}, "stats", []string{}},
+		{[]string{"--timeout", "5s", "simulate", "-n", "10"}, "simulate", []string{"--timeout", "5s", "-n", "10"}},
+		{[]string{"--timeout=5s", "HH"}, "HH", []string{"--timeout=5s"}},
+		// A boolean flag doesn't take the command name as its value
+		{[]string{"--kanga", "dump-csv", "out"}, "dump-csv", []string{"--kanga", "out"}},
+		{[]string{"--", "stats"}, "help", []string{"--", "stats"}},
+		{nil, "help", nil},
+	}
+	for _, tt := range tests {
+		name, rest := splitCommand(tt.args)
+		if name != tt.name || !reflect.DeepEqual(rest, tt.rest) {
+			t.Errorf("splitCommand(%q) = %q, %q, want %q, %q", tt.args, name, rest, tt.name, tt.rest)
+		}
+	}
+}
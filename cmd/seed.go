@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/alexstory/kanga/data"
+	"github.com/alexstory/kanga/sim"
+)
+
+// seedChunk is the number of entries per card inserted in one transaction.
+const seedChunk = 10000
+
+// seedMatteredRate is the share of generated exeggutor flips that mattered.
+const seedMatteredRate = 0.8
+
+// seedFlips maps the first and second coin of a generated attack to its
+// flip type.
+var seedFlips = [2][2]data.FlipType{
+	{data.TT, data.TH},
+	{data.HT, data.HH},
+}
+
+// defaultSeedPath returns kanga-seed.db, next to the default database.
+func defaultSeedPath() (string, error) {
+	path, err := data.DefaultPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "kanga-seed.db"), nil
+}
+
+// Seed creates a database at path holding n generated entries per card.
+// Coins land heads with probability bias, and entries are spread evenly
+// over the span ending at end. The same seed and end generate the same
+// results. Entries are logged for the profile given with --profile.
+// The database is built in a temporary file next to path and only renamed
+// to path once complete, so a failed run leaves nothing behind.
+func Seed(ctx context.Context, inv *Invocation, path string, n int, bias float64, seed uint64, end time.Time, span time.Duration) error {
+	if n <= 0 {
+		return usageError("seed", "the number of entries must be positive")
+	}
+	// Misty flips until tails, so a bias of 1 would never end an attempt
+	if bias < 0 || bias >= 1 {
+		return usageError("seed", "the bias must be at least 0 and below 1")
+	}
+	if err := inv.requireProfile("seed"); err != nil {
+		return err
+	}
+	if path == "" {
+		var err error
+		if path, err = defaultSeedPath(); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(path); err == nil {
+		return usageError("seed", "%s already exists, remove it or pick another --out", path)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.partial")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	if err := seedInto(ctx, inv, tmp.Name(), n, bias, seed, end.Add(-span), span); err != nil {
+		removeDatabase(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		removeDatabase(tmp.Name())
+		return fmt.Errorf("failed to move the seeded database to %s: %w", path, err)
+	}
+
+	fmt.Printf("Seeded %d flips, %d exeggutor entries and %d misty entries into %s\n", n, n, n, path)
+	fmt.Printf("Run commands against it with --db %s\n", path)
+	return nil
+}
+
+// seedInto writes the generated entries of Seed to the database at path,
+// spread over the span from start.
+func seedInto(ctx context.Context, inv *Invocation, path string, n int, bias float64, seed uint64, start time.Time, span time.Duration) error {
+	store, err := data.Open(ctx, path)
+	if err != nil {
+		return &storeError{err}
+	}
+	defer store.Close()
+	if inv.Profile != data.DefaultProfile {
+		if err := store.AddProfile(ctx, inv.Profile); err != nil {
+			return err
+		}
+	}
+	if inv.Deck != "" {
+		if err := store.AddDeck(ctx, inv.Deck); err != nil {
+			return err
+		}
+	}
+
+	r := rand.New(rand.NewPCG(seed, seed))
+	for done := 0; done < n; done += seedChunk {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		size := min(seedChunk, n-done)
+		batches := make([]data.Batch, 0, size*3)
+		for i := done; i < done+size; i++ {
+			// Entry i of every card lands somewhere in the i-th slice of the span
+			at := func() data.Meta {
+				offset := (float64(i) + r.Float64()) / float64(n) * float64(span)
+				return data.Meta{At: start.Add(time.Duration(offset)), Profile: inv.Profile, Deck: inv.Deck}
+			}
+			eggType := data.T
+			if sim.Flip(r, bias) == 1 {
+				eggType = data.H
+			}
+			if r.Float64() >= seedMatteredRate {
+				eggType = map[data.EggType]data.EggType{data.H: data.HX, data.T: data.TX}[eggType]
+			}
+			batches = append(batches,
+				data.Batch{Flips: []data.FlipType{seedFlips[sim.Flip(r, bias)][sim.Flip(r, bias)]}, Meta: at()},
+				data.Batch{Eggs: []data.EggType{eggType}, Meta: at()},
+				data.Batch{Misty: []int{sim.Attack(r, data.Misty, bias)}, Meta: at()},
+			)
+		}
+		if err := store.InsertBatch(ctx, batches...); err != nil {
+			return fmt.Errorf("failed to insert generated entries: %w", err)
+		}
+	}
+	return store.Close()
+}
+
+// removeDatabase removes a database file along with its WAL files.
+func removeDatabase(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(path + suffix)
+	}
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexstory/kanga/data"
+)
+
+// periodLayouts are the layouts of a period selector, from the most to the
+// least precise, with the length of the period they name.
+var periodLayouts = []struct {
+	layout string
+	next   func(time.Time) time.Time
+}{
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+}
+
+// parseSelector parses a selector: comma separated terms that entries must
+// all match. A term is one of:
+//
+//	all                    every entry of the profile in use
+//	tag:NAME               entries tagged NAME
+//	profile:NAME           entries of profile NAME, or of every profile with
+//	                       profile:all, instead of the profile in use
+//	deck:NAME              entries played with deck NAME, or without a deck
+//	                       with deck:none
+//	vs:NAME                entries played against opponent archetype NAME
+//	last:SPAN              entries of the last SPAN, e.g. last:30d
+//	2026-09, 2026-09-14    entries of a year, month or day
+//	2026-Q3                entries of a quarter, from July to September
+//	FROM..TO               entries logged from FROM up to TO; either side may
+//	                       be omitted, and a TO without a time of day
+//	                       includes that whole day
+//
+// Terms refine base, the filter of the profile in use. Dates and times are
+// read like --at, in the time zone of now.
+func parseSelector(s string, base data.Filter, now time.Time) (data.Filter, error) {
+	filter := base
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			return data.Filter{}, fmt.Errorf("empty term in selector %q", s)
+		}
+		if term == "all" {
+			continue
+		}
+		if tag, ok := strings.CutPrefix(term, "tag:"); ok {
+			tags, err := data.NormalizeTags([]string{tag})
+			if err != nil {
+				return data.Filter{}, err
+			}
+			filter.Tags = append(filter.Tags, tags...)
+			continue
+		}
+
+		if name, ok := strings.CutPrefix(term, "profile:"); ok {
+			if name == data.AllProfiles {
+				filter.Profile = ""
+				continue
+			}
+			profile, err := data.NormalizeProfile(name)
+			if err != nil {
+				return data.Filter{}, err
+			}
+			filter.Profile = profile
+			continue
+		}
+		if name, ok := strings.CutPrefix(term, "deck:"); ok {
+			if name == data.NoDeck {
+				filter.Deck = data.NoDeck
+				continue
+			}
+			deck, err := data.NormalizeDeck(name)
+			if err != nil {
+				return data.Filter{}, err
+			}
+			filter.Deck = deck
+			continue
+		}
+		if name, ok := strings.CutPrefix(term, "vs:"); ok {
+			opponent, err := data.NormalizeOpponent(name)
+			if err != nil {
+				return data.Filter{}, err
+			}
+			filter.Opponent = opponent
+			continue
+		}
+		if span, ok := strings.CutPrefix(term, "last:"); ok {
+			// Same as -SPAN.., which would be read as a flag on its own.
+			term = "-" + span + ".."
+		}
+
+		from, to, err := parsePeriod(term, now)
+		if err != nil {
+			return data.Filter{}, err
+		}
+		if !from.IsZero() && (filter.From.IsZero() || from.After(filter.From)) {
+			filter.From = from
+		}
+		if !to.IsZero() && (filter.To.IsZero() || to.Before(filter.To)) {
+			filter.To = to
+		}
+	}
+	return filter, nil
+}
+
+// parsePeriod parses a FROM..TO range or a single year, quarter, month or
+// day.
+func parsePeriod(term string, now time.Time) (from, to time.Time, err error) {
+	if start, end, ok := strings.Cut(term, ".."); ok {
+		if start != "" {
+			if from, err = parseAt(start, now); err != nil {
+				return time.Time{}, time.Time{}, err
+			}
+		}
+		if end != "" {
+			if to, err = parseAt(end, now); err != nil {
+				return time.Time{}, time.Time{}, err
+			}
+			if _, err := time.ParseInLocation("2006-01-02", end, now.Location()); err == nil {
+				to = to.AddDate(0, 0, 1)
+			}
+		}
+		if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+			return time.Time{}, time.Time{}, fmt.Errorf("empty range %q", term)
+		}
+		return from, to, nil
+	}
+	if t, ok := parseQuarter(term, now.Location()); ok {
+		return t, t.AddDate(0, 3, 0), nil
+	}
+	for _, p := range periodLayouts {
+		if t, err := time.ParseInLocation(p.layout, term, now.Location()); err == nil {
+			return t, p.next(t), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid selector term %q", term)
+}
+
+// parseQuarter parses a quarter of a year such as 2026-Q3, returning its
+// first day.
+func parseQuarter(term string, loc *time.Location) (time.Time, bool) {
+	year, quarter, ok := strings.Cut(strings.ToUpper(term), "-Q")
+	if !ok || len(year) != 4 || len(quarter) != 1 || quarter < "1" || quarter > "4" {
+		return time.Time{}, false
+	}
+	y, err := strconv.Atoi(year)
+	if err != nil {
+		return time.Time{}, false
+	}
+	first := time.Month(3*int(quarter[0]-'0') - 2)
+	return time.Date(y, first, 1, 0, 0, 0, 0, loc), true
+}
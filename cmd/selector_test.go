@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alexstory/kanga/data"
+)
+
+func TestParseSelector(t *testing.T) {
+	now := time.Date(2026, 10, 12, 15, 0, 0, 0, time.UTC)
+	base := data.Filter{Profile: "alice"}
+	day := func(month time.Month, d int) time.Time { return time.Date(2026, month, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		s    string
+		want data.Filter
+		ok   bool
+	}{
+		{"all", base, true},
+		{"tag:Tournament,tag:casual", data.Filter{Profile: "alice", Tags: []string{"tournament", "casual"}}, true},
+		{"profile:bob", data.Filter{Profile: "bob"}, true},
+		{"profile:all,tag:x", data.Filter{Tags: []string{"x"}}, true},
+		{"profile:bob,deck:none", data.Filter{Profile: "bob", Deck: data.NoDeck}, true},
+		{"deck:Haymaker", data.Filter{Profile: "alice", Deck: "haymaker"}, true},
+		{"profile:all,vs:mewtwo", data.Filter{Opponent: "mewtwo"}, true},
+		{"2026-09", data.Filter{Profile: "alice", From: day(9, 1), To: day(10, 1)}, true},
+		{"2026-09-14", data.Filter{Profile: "alice", From: day(9, 14), To: day(9, 15)}, true},
+		{"2026-Q3", data.Filter{Profile: "alice", From: day(7, 1), To: day(10, 1)}, true},
+		{"2026-q4,tag:x", data.Filter{Profile: "alice", Tags: []string{"x"}, From: day(10, 1), To: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)}, true},
+		{"last:2d", data.Filter{Profile: "alice", From: now.Add(-48 * time.Hour)}, true},
+		// A TO without a time of day includes that whole day
+		{"2026-09-01..2026-09-30", data.Filter{Profile: "alice", From: day(9, 1), To: day(10, 1)}, true},
+		{"..2026-09-30 12:00", data.Filter{Profile: "alice", To: time.Date(2026, 9, 30, 12, 0, 0, 0, time.UTC)}, true},
+		// Periods intersect
+		{"2026,2026-09", data.Filter{Profile: "alice", From: day(9, 1), To: day(10, 1)}, true},
+		{"2026-09-30..2026-09-01", data.Filter{}, false},
+		{"tag:a b", data.Filter{}, false},
+		{"profile:", data.Filter{}, false},
+		{"deck:", data.Filter{}, false},
+		{"vs:", data.Filter{}, false},
+		{"all,,tag:x", data.Filter{}, false},
+		{"someday", data.Filter{}, false},
+		{"2026-Q5", data.Filter{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parseSelector(tt.s, base, now)
+		if (err == nil) != tt.ok || (tt.ok && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("parseSelector(%q) = %+v, %v, want %+v, ok %v", tt.s, got, err, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alexstory/kanga/data"
+	"github.com/alexstory/kanga/sim"
+	"github.com/alexstory/kanga/stats"
+)
+
+// observedHistogram returns the logged attacks of a card as a histogram of
+// heads per attack.
+func observedHistogram(ctx context.Context, store data.Store, card data.TableType, filter data.Filter) ([]int, error) {
+	switch card {
+	case data.Kanga:
+		s, err := store.Flips(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		attacks := s.TotalFlips / 2
+		return []int{s.DoubleTails, attacks - s.DoubleHeads - s.DoubleTails, s.DoubleHeads}, nil
+	case data.Egg:
+		s, err := store.GetEggStats(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		return []int{s.TotalTails, s.TotalHeads}, nil
+	case data.Misty:
+		return store.GetMistyHistogram(ctx, filter)
+	}
+	return nil, fmt.Errorf("unknown card")
+}
+
+func meanDamage(card data.TableType, counts []int) float64 {
+	total := stats.Total(counts)
+	if total == 0 {
+		return 0
+	}
+	sum := 0
+	for k, c := range counts {
+		sum += data.Damage(card, k) * c
+	}
+	return float64(sum) / float64(total)
+}
+
+func share(counts []int, k int) float64 {
+	if k < len(counts) {
+		return percentage(counts[k], stats.Total(counts))
+	}
+	return 0
+}
+
+func shareAtLeast(counts []int, k int) float64 {
+	sum := 0
+	for i := k; i < len(counts); i++ {
+		sum += counts[i]
+	}
+	return percentage(sum, stats.Total(counts))
+}
+
+func outcomeLabel(card data.TableType, heads int) string {
+	switch card {
+	case data.Egg:
+		if heads == 0 {
+			return fmt.Sprintf("Tails (%d dmg)", data.Damage(card, heads))
+		}
+		return fmt.Sprintf("Heads (%d dmg)", data.Damage(card, heads))
+	case data.Kanga:
+		return fmt.Sprintf("%d heads (%d dmg)", heads, data.Damage(card, heads))
+	}
+	return fmt.Sprintf("%d heads", heads)
+}
+
+// Simulate runs n attacks of the card given in args and prints them next to
+// the logged results matching filter. A zero seed picks one from the clock.
+func Simulate(ctx context.Context, store data.Store, args []string, n int, seed uint64, filter data.Filter) error {
+	if len(args) < 1 {
+		PrintHelp("simulate")
+		return usageError("simulate", "missing card")
+	}
+	card, ok := parseCard(args[0])
+	if !ok {
+		return usageError("simulate", "invalid card %q for simulate command", args[0])
+	}
+	if n <= 0 {
+		return usageError("simulate", "the number of simulated attacks must be positive")
+	}
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+
+	observed, err := observedHistogram(ctx, store, card, filter)
+	if err != nil {
+		return fmt.Errorf("failed to get observed results: %w", err)
+	}
+	result := sim.Run(card, n, seed)
+
+	dataPairs := []LabelValuePair{
+		{"Seed", fmt.Sprintf("%d", seed)},
+		{"Simulated attacks", fmt.Sprintf("%d", result.Attacks)},
+		{"Observed attacks", fmt.Sprintf("%d", stats.Total(observed))},
+		{"Outcome", "sim / obs"},
+	}
+
+	buckets := len(result.Heads)
+	switch card {
+	case data.Kanga:
+		buckets = 3
+	case data.Egg:
+		buckets = 2
+	case data.Misty:
+		// The last bucket folds every higher count, as in the fit
+		buckets = mistyBuckets - 1
+	}
+	for k := 0; k < buckets; k++ {
+		dataPairs = append(dataPairs, LabelValuePair{outcomeLabel(card, k),
+			fmt.Sprintf("%.2f%% / %.2f%%", share(result.Heads, k), share(observed, k))})
+	}
+	if card == data.Misty {
+		dataPairs = append(dataPairs, LabelValuePair{fmt.Sprintf("%d+ heads", buckets),
+			fmt.Sprintf("%.2f%% / %.2f%%", shareAtLeast(result.Heads, buckets), shareAtLeast(observed, buckets))})
+	}
+
+	simMean, simVar := stats.MeanVariance(result.Heads)
+	obsMean, _ := stats.MeanVariance(observed)
+	dataPairs = append(dataPairs, LabelValuePair{"Average heads", fmt.Sprintf("%.3f / %.3f", simMean, obsMean)})
+	// Misty attaches an energy per heads, so its average heads is its
+	// average energy
+	if card != data.Misty {
+		dataPairs = append(dataPairs, LabelValuePair{"Average damage",
+			fmt.Sprintf("%.1f / %.1f", meanDamage(card, result.Heads), meanDamage(card, observed))})
+	}
+
+	if stats.Total(observed) > 0 {
+		pct := stats.MeanPercentile(obsMean, simMean, simVar, stats.Total(observed))
+		dataPairs = append(dataPairs, LabelValuePair{"Luck percentile", fmt.Sprintf("%.1f%%", pct)})
+	}
+	printTable("SIMULATED "+cardTitle(card), dataPairs)
+	return nil
+}
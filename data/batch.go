@@ -0,0 +1,85 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+func (s *SQLiteStore) InsertBatch(ctx context.Context, batches ...Batch) error {
+	batches, err := validateBatches(batches)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	flips := &rowInserter{tx: tx, table: "flips", columns: []string{"heads1", "heads2", "created_at", "note", "tags", "profile", "deck", "opponent"}}
+	eggs := &rowInserter{tx: tx, table: "exeggutor", columns: []string{"heads", "mattered", "created_at", "note", "tags", "profile", "deck", "opponent"}}
+	misty := &rowInserter{tx: tx, table: "misty", columns: []string{"heads", "created_at", "note", "tags", "profile", "deck", "opponent"}}
+	for _, batch := range batches {
+		tags, createdAt := encodeTags(batch.Meta.Tags), batch.Meta.createdAt()
+		for _, flipType := range batch.Flips {
+			heads1, heads2, _ := flipHeads(flipType)
+			if err := flips.add(ctx, heads1, heads2, createdAt, batch.Meta.Note, tags, batch.Meta.Profile, batch.Meta.Deck, batch.Meta.Opponent); err != nil {
+				return fmt.Errorf("failed to insert flip: %w", err)
+			}
+		}
+		for _, eggType := range batch.Eggs {
+			heads, mattered, _ := eggValues(eggType)
+			if err := eggs.add(ctx, heads, mattered, createdAt, batch.Meta.Note, tags, batch.Meta.Profile, batch.Meta.Deck, batch.Meta.Opponent); err != nil {
+				return fmt.Errorf("failed to insert exeggutor entry: %w", err)
+			}
+		}
+		for _, heads := range batch.Misty {
+			if err := misty.add(ctx, heads, createdAt, batch.Meta.Note, tags, batch.Meta.Profile, batch.Meta.Deck, batch.Meta.Opponent); err != nil {
+				return fmt.Errorf("failed to insert misty entry: %w", err)
+			}
+		}
+	}
+	for _, w := range []*rowInserter{flips, eggs, misty} {
+		if err := w.flush(ctx); err != nil {
+			return fmt.Errorf("failed to insert into %s: %w", w.table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// insertRowsPerStatement is how many rows a rowInserter writes with a single
+// INSERT. Every statement is compiled along with the counter triggers, so
+// large batches are much faster written many rows at a time.
+const insertRowsPerStatement = 200
+
+// rowInserter buffers rows for a table and inserts them many at a time, in
+// the order they were added.
+type rowInserter struct {
+	tx      *sql.Tx
+	table   string
+	columns []string
+	values  []any
+}
+
+func (w *rowInserter) add(ctx context.Context, values ...any) error {
+	w.values = append(w.values, values...)
+	if len(w.values) < insertRowsPerStatement*len(w.columns) {
+		return nil
+	}
+	return w.flush(ctx)
+}
+
+func (w *rowInserter) flush(ctx context.Context) error {
+	if len(w.values) == 0 {
+		return nil
+	}
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(w.columns)), ", ") + ")"
+	rows := strings.TrimSuffix(strings.Repeat(row+", ", len(w.values)/len(w.columns)), ", ")
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", w.table, strings.Join(w.columns, ", "), rows)
+	_, err := w.tx.ExecContext(ctx, query, w.values...)
+	w.values = w.values[:0]
+	return err
+}
@@ -0,0 +1,329 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Problem is an inconsistency found in the database by Check.
+type Problem struct {
+	Table string
+	// ID is the row the problem is about, or 0 for problems with the table.
+	ID      int64
+	Message string
+	// Fix describes the repair Repair applies. It is empty when the problem
+	// has to be fixed by hand.
+	Fix string
+
+	repair func(ctx context.Context, q querier) error
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// valueColumn is a column holding a small integer, along with how to read
+// the values older imports may have left in it.
+type valueColumn struct {
+	table, column string
+	// valid is an SQL condition on the column for values in range.
+	valid string
+	parse func(s string) (int, bool)
+}
+
+var valueColumns = []valueColumn{
+	{"flips", "heads1", "IN (0, 1)", parseBit},
+	{"flips", "heads2", "IN (0, 1)", parseBit},
+	{"exeggutor", "heads", "IN (0, 1)", parseBit},
+	{"exeggutor", "mattered", "IN (0, 1)", parseBit},
+	{"misty", "heads", ">= 0", parseCount},
+}
+
+// resultColumns are the columns holding the result of an entry, per table.
+var resultColumns = map[string][]string{
+	"flips":     {"heads1", "heads2"},
+	"exeggutor": {"heads", "mattered"},
+	"misty":     {"heads"},
+}
+
+// expectedColumns is the schema of every table.
+var expectedColumns = map[string][]string{
+	"flips":     {"id", "heads1", "heads2", "created_at", "note", "tags", "profile", "game_id", "deck", "opponent"},
+	"exeggutor": {"id", "heads", "mattered", "created_at", "note", "tags", "profile", "game_id", "deck", "opponent"},
+	"misty":     {"id", "heads", "created_at", "note", "tags", "profile", "game_id", "deck", "opponent"},
+}
+
+var checkedTables = []string{"flips", "exeggutor", "misty"}
+
+// parseBit reads the ways a 0 or 1 ends up written in CSV files.
+func parseBit(s string) (int, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "1.0", "true", "yes", "heads":
+		return 1, true
+	case "0", "0.0", "false", "no", "tails":
+		return 0, true
+	}
+	return 0, false
+}
+
+// parseCount reads a number of heads.
+func parseCount(s string) (int, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || f < 0 || f != float64(int(f)) {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// Check scans every table for values out of range, impossible timestamps,
+// duplicated rows, rows of unknown profiles, decks or games and schema
+// drift, and checks the stats counters.
+func (s *SQLiteStore) Check(ctx context.Context) ([]Problem, error) {
+	return check(ctx, s.db)
+}
+
+// Repair backs the database up, then fixes every problem it can inside a
+// single transaction. It returns the problems found and the path of the
+// backup.
+func (s *SQLiteStore) Repair(ctx context.Context) ([]Problem, string, error) {
+	backup := fmt.Sprintf("%s.%s.bak", s.path, time.Now().Format("20060102-150405"))
+	if err := s.Backup(ctx, backup); err != nil {
+		return nil, "", fmt.Errorf("failed to back up the database: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, backup, err
+	}
+	defer tx.Rollback()
+
+	problems, err := check(ctx, tx)
+	if err != nil {
+		return nil, backup, err
+	}
+	for _, p := range problems {
+		if p.repair == nil {
+			continue
+		}
+		if err := p.repair(ctx, tx); err != nil {
+			return nil, backup, fmt.Errorf("failed to fix %s #%d: %w", p.Table, p.ID, err)
+		}
+	}
+	return problems, backup, tx.Commit()
+}
+
+// Backup writes a copy of the database to path.
+func (s *SQLiteStore) Backup(ctx context.Context, path string) error {
+	_, err := s.db.ExecContext(ctx, "VACUUM INTO ?", path)
+	return err
+}
+
+func check(ctx context.Context, q querier) ([]Problem, error) {
+	var problems []Problem
+	for _, f := range []func(context.Context, querier) ([]Problem, error){
+		checkSchema, checkValues, checkTimestamps, checkDuplicates, checkProfiles, checkDecks, checkGames, checkCounters,
+	} {
+		found, err := f(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, found...)
+	}
+	return problems, nil
+}
+
+func checkSchema(ctx context.Context, q querier) ([]Problem, error) {
+	var problems []Problem
+	var integrity []string
+	err := queryRows(ctx, q, "PRAGMA integrity_check", nil, func(rows *sql.Rows) error {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return err
+		}
+		if line != "ok" {
+			integrity = append(integrity, line)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range integrity {
+		problems = append(problems, Problem{Message: "integrity check: " + line})
+	}
+
+	for _, table := range checkedTables {
+		var columns []string
+		err := queryRows(ctx, q, "SELECT name FROM pragma_table_info(?)", []any{table}, func(rows *sql.Rows) error {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			columns = append(columns, name)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			problems = append(problems, Problem{Table: table, Message: "table is missing"})
+			continue
+		}
+		for _, column := range expectedColumns[table] {
+			if !slices.Contains(columns, column) {
+				problems = append(problems, Problem{Table: table, Message: fmt.Sprintf("column %s is missing", column)})
+			}
+		}
+		for _, column := range columns {
+			if !slices.Contains(expectedColumns[table], column) {
+				problems = append(problems, Problem{Table: table, Message: fmt.Sprintf("unexpected column %s", column)})
+			}
+		}
+	}
+	return problems, nil
+}
+
+func checkValues(ctx context.Context, q querier) ([]Problem, error) {
+	var problems []Problem
+	for _, c := range valueColumns {
+		query := fmt.Sprintf("SELECT id, CAST(%[2]s AS TEXT) FROM %[1]s WHERE typeof(%[2]s) != 'integer' OR NOT %[2]s %[3]s ORDER BY id",
+			c.table, c.column, c.valid)
+		err := queryRows(ctx, q, query, nil, func(rows *sql.Rows) error {
+			var id int64
+			var value sql.NullString
+			if err := rows.Scan(&id, &value); err != nil {
+				return err
+			}
+			p := Problem{Table: c.table, ID: id, Message: fmt.Sprintf("invalid %s %s", c.column, quoteValue(value))}
+			if v, ok := c.parse(value.String); value.Valid && ok {
+				p.Fix = fmt.Sprintf("set %s to %d", c.column, v)
+				p.repair = updateColumn(c.table, c.column, id, v)
+			} else {
+				p.Fix = "delete the row"
+				p.repair = deleteRow(c.table, id)
+			}
+			problems = append(problems, p)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return problems, nil
+}
+
+func checkTimestamps(ctx context.Context, q querier) ([]Problem, error) {
+	var problems []Problem
+	latest := FormatTime(time.Now().Add(maxClockSkew))
+	for _, table := range checkedTables {
+		query := fmt.Sprintf(`SELECT id, CAST(created_at AS TEXT) FROM %s
+			WHERE created_at IS NULL OR created_at NOT GLOB ? OR created_at < ? OR created_at > ?
+			ORDER BY id`, table)
+		args := []any{storedTimeGlob, FormatTime(earliestTime), latest}
+		err := queryRows(ctx, q, query, args, func(rows *sql.Rows) error {
+			var id int64
+			var value sql.NullString
+			if err := rows.Scan(&id, &value); err != nil {
+				return err
+			}
+			p := Problem{Table: table, ID: id, Fix: "delete the row", repair: deleteRow(table, id)}
+			t, err := ParseTime(value.String)
+			if !value.Valid || err != nil {
+				p.Message = "invalid timestamp " + quoteValue(value)
+			} else if err := validateTime(t); err != nil {
+				p.Message = err.Error()
+			} else {
+				p.Message = fmt.Sprintf("timestamp %s is not in UTC RFC 3339", value.String)
+				p.Fix = "rewrite it as " + FormatTime(t)
+				p.repair = updateColumn(table, "created_at", id, FormatTime(t))
+			}
+			problems = append(problems, p)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return problems, nil
+}
+
+// checkDuplicates finds rows identical to an earlier row, timestamp
+// included, and reports them as duplicates of the first of their group.
+// Results logged in one batch share their timestamp, so identical rows only
+// count as duplicates once a row logged at another time sits between them
+// and the first, as happens when a CSV file is read twice.
+func checkDuplicates(ctx context.Context, q querier) ([]Problem, error) {
+	var problems []Problem
+	for _, table := range checkedTables {
+		columns := append([]string{"created_at", "note", "tags", "profile", "deck", "opponent"}, resultColumns[table]...)
+		same := make([]string, len(columns))
+		for i, column := range columns {
+			same[i] = fmt.Sprintf("r.%[1]s IS d.%[1]s", column)
+		}
+		query := fmt.Sprintf(`WITH d AS (SELECT MIN(id) AS first, %[2]s FROM %[1]s GROUP BY %[2]s HAVING COUNT(*) > 1)
+			SELECT r.id, d.first FROM d JOIN %[1]s r ON %[3]s
+			WHERE r.id > (SELECT MIN(m.id) FROM %[1]s m WHERE m.id > d.first AND m.created_at IS NOT d.created_at)
+			ORDER BY r.id`, table, strings.Join(columns, ", "), strings.Join(same, " AND "))
+		err := queryRows(ctx, q, query, nil, func(rows *sql.Rows) error {
+			var id, original int64
+			if err := rows.Scan(&id, &original); err != nil {
+				return err
+			}
+			problems = append(problems, Problem{
+				Table:   table,
+				ID:      id,
+				Message: fmt.Sprintf("duplicate of #%d", original),
+				Fix:     "delete the row",
+				repair:  deleteRow(table, id),
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return problems, nil
+}
+
+// queryRows runs query and calls scan for every row.
+func queryRows(ctx context.Context, q querier, query string, args []any, scan func(*sql.Rows) error) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func updateColumn(table, column string, id int64, value any) func(context.Context, querier) error {
+	return func(ctx context.Context, q querier) error {
+		_, err := q.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", table, column), value, id)
+		return err
+	}
+}
+
+func deleteRow(table string, id int64) func(context.Context, querier) error {
+	return func(ctx context.Context, q querier) error {
+		_, err := q.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = ?", table), id)
+		return err
+	}
+}
+
+func quoteValue(v sql.NullString) string {
+	if !v.Valid {
+		return "NULL"
+	}
+	return strconv.Quote(v.String)
+}
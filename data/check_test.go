@@ -0,0 +1,101 @@
+package data
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openTestStore opens a new database in a temporary folder.
+func openTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	store, err := Open(context.Background(), filepath.Join(t.TempDir(), "kanga.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestCheckAndRepair(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	rows := []string{
+		// #1, #2 and #3 of flips: #3 duplicates #1 with another row between
+		"INSERT INTO flips (heads1, heads2, created_at) VALUES (1, 0, '2026-01-02T03:04:05Z')",
+		"INSERT INTO flips (heads1, heads2, created_at) VALUES (0, 0, '2026-01-02T04:00:00Z')",
+		"INSERT INTO flips (heads1, heads2, created_at) VALUES (1, 0, '2026-01-02T03:04:05Z')",
+		// #4: a value written as text by an old import
+		"INSERT INTO flips (heads1, heads2, created_at) VALUES ('true', 0, '2026-01-02T05:00:00Z')",
+		// #5: a timestamp in the legacy layout
+		"INSERT INTO flips (heads1, heads2, created_at) VALUES (1, 1, '2026-01-02 06:00:00')",
+		"INSERT INTO misty (heads, created_at) VALUES ('lots', '2026-01-02T05:00:00Z')",
+		// #2 to #5 of misty: #2 and #3 are a batch, #5 duplicates them once
+		// #4 is logged
+		"INSERT INTO misty (heads, created_at) VALUES (1, '2026-01-03T00:00:00Z')",
+		"INSERT INTO misty (heads, created_at) VALUES (1, '2026-01-03T00:00:00Z')",
+		"INSERT INTO misty (heads, created_at) VALUES (2, '2026-01-03T01:00:00Z')",
+		"INSERT INTO misty (heads, created_at) VALUES (1, '2026-01-03T00:00:00Z')",
+		"INSERT INTO exeggutor (heads, mattered, created_at) VALUES (1, 1, 'garbage')",
+	}
+	for _, row := range rows {
+		if _, err := s.db.ExecContext(ctx, row); err != nil {
+			t.Fatalf("%s: %v", row, err)
+		}
+	}
+
+	want := []struct {
+		table   string
+		id      int64
+		message string
+		fix     string
+	}{
+		{"flips", 4, `invalid heads1 "true"`, "set heads1 to 1"},
+		{"misty", 1, `invalid heads "lots"`, "delete the row"},
+		{"flips", 5, "not in UTC RFC 3339", "rewrite it as 2026-01-02T06:00:00Z"},
+		{"exeggutor", 1, `invalid timestamp "garbage"`, "delete the row"},
+		{"flips", 3, "duplicate of #1", "delete the row"},
+		{"misty", 5, "duplicate of #2", "delete the row"},
+	}
+	problems, err := s.Check(ctx)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(problems) != len(want) {
+		t.Errorf("Check found %d problems, want %d: %+v", len(problems), len(want), problems)
+	}
+	for _, w := range want {
+		found := false
+		for _, p := range problems {
+			found = found || (p.Table == w.table && p.ID == w.id && strings.Contains(p.Message, w.message) && p.Fix == w.fix)
+		}
+		if !found {
+			t.Errorf("Check didn't report %s #%d %q with fix %q, got %+v", w.table, w.id, w.message, w.fix, problems)
+		}
+	}
+
+	repaired, backup, err := s.Repair(ctx)
+	if err != nil {
+		t.Fatalf("Repair: %v", err)
+	}
+	if len(repaired) != len(problems) {
+		t.Errorf("Repair fixed %d problems, want %d", len(repaired), len(problems))
+	}
+	if _, err := os.Stat(backup); err != nil {
+		t.Errorf("Repair didn't write its backup: %v", err)
+	}
+	if problems, err := s.Check(ctx); err != nil || len(problems) != 0 {
+		t.Errorf("Check after Repair = %+v, %v, want no problems", problems, err)
+	}
+	checkCountersMatch(t, s, "repair")
+
+	flip, err := s.GetFlip(ctx, 4)
+	if err != nil || flip.Heads1 != 1 {
+		t.Errorf("GetFlip(4) after Repair = %+v, %v, want heads1 1", flip, err)
+	}
+	if _, err := s.GetFlip(ctx, 3); err == nil {
+		t.Errorf("duplicate flip #3 survived Repair")
+	}
+}
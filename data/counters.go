@@ -0,0 +1,308 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// counter is an aggregate kept in the stats_counters table. expr is the
+// amount a row adds to it, with the row's columns prefixed by "r.".
+type counter struct {
+	name, expr string
+}
+
+// tableCounters lists the counters of every table. Triggers keep them up to
+// date, so unfiltered stats don't have to scan the tables.
+var tableCounters = map[string][]counter{
+	"flips": {
+		{"rows", "1"},
+		{"heads", "r.heads1 + r.heads2"},
+		{"tails", "(1 - r.heads1) + (1 - r.heads2)"},
+		{"double_heads", "r.heads1 = 1 AND r.heads2 = 1"},
+		{"double_tails", "r.heads1 = 0 AND r.heads2 = 0"},
+	},
+	"exeggutor": {
+		{"rows", "1"},
+		{"heads", "r.heads"},
+		{"tails", "r.heads = 0"},
+		{"not_mattered", "r.mattered = 0"},
+		{"heads_mattered", "r.heads = 1 AND r.mattered = 1"},
+	},
+	"misty": {
+		{"rows", "1"},
+		{"heads", "r.heads"},
+	},
+}
+
+// createCounters creates the stats_counters table and the triggers that
+// maintain it, and fills it in when it is new. Counters are kept per
+// profile; a table from before profiles is dropped and rebuilt.
+func createCounters(ctx context.Context, db *sql.DB) error {
+	var exists, perProfile bool
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'stats_counters'").Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		err := db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM pragma_table_info('stats_counters') WHERE name = 'profile'").Scan(&perProfile)
+		if err != nil {
+			return err
+		}
+	}
+	if exists && !perProfile {
+		if err := dropCounters(ctx, db); err != nil {
+			return err
+		}
+	}
+
+	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS stats_counters (
+		table_name TEXT NOT NULL,
+		profile TEXT NOT NULL,
+		counter TEXT NOT NULL,
+		value INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (table_name, profile, counter)
+	);`)
+	if err != nil {
+		return err
+	}
+	for _, table := range checkedTables {
+		for _, trigger := range counterTriggers(table) {
+			if _, err := db.ExecContext(ctx, trigger); err != nil {
+				return err
+			}
+		}
+	}
+
+	if exists && perProfile {
+		return nil
+	}
+	return rebuildCounters(ctx, db)
+}
+
+// dropCounters drops the stats_counters table and its triggers.
+func dropCounters(ctx context.Context, db *sql.DB) error {
+	for _, table := range checkedTables {
+		for _, event := range []string{"insert", "delete", "update"} {
+			if _, err := db.ExecContext(ctx, fmt.Sprintf("DROP TRIGGER IF EXISTS %s_counters_%s", table, event)); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := db.ExecContext(ctx, "DROP TABLE stats_counters")
+	return err
+}
+
+// counterTriggers returns the statements creating the triggers that keep the
+// counters of table up to date.
+func counterTriggers(table string) []string {
+	delta := func(row string) string {
+		cases := make([]string, len(tableCounters[table]))
+		for i, c := range tableCounters[table] {
+			cases[i] = fmt.Sprintf("WHEN '%s' THEN IFNULL(%s, 0)", c.name, strings.ReplaceAll(c.expr, "r.", row+"."))
+		}
+		return "CASE counter " + strings.Join(cases, " ") + " ELSE 0 END"
+	}
+	// create makes sure the counters of the row's profile exist.
+	create := func(row string) string {
+		values := make([]string, len(tableCounters[table]))
+		for i, c := range tableCounters[table] {
+			values[i] = fmt.Sprintf("('%s', %s.profile, '%s')", table, row, c.name)
+		}
+		return "INSERT OR IGNORE INTO stats_counters (table_name, profile, counter) VALUES " + strings.Join(values, ", ") + ";"
+	}
+	update := func(row, sign string) string {
+		return fmt.Sprintf("UPDATE stats_counters SET value = value %s %s WHERE table_name = '%s' AND profile = %s.profile;",
+			sign, delta(row), table, row)
+	}
+	trigger := func(event string, body ...string) string {
+		return fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_counters_%[2]s AFTER %[3]s ON %[1]s BEGIN
+			%[4]s
+		END;`, table, strings.ToLower(event), event, strings.Join(body, "\n\t\t\t"))
+	}
+	return []string{
+		trigger("INSERT", create("NEW"), update("NEW", "+")),
+		trigger("DELETE", update("OLD", "-")),
+		trigger("UPDATE", update("OLD", "-"), create("NEW"), update("NEW", "+")),
+	}
+}
+
+// RebuildCounters recomputes every stats counter from the tables.
+func (s *SQLiteStore) RebuildCounters(ctx context.Context) error {
+	return rebuildCounters(ctx, s.db)
+}
+
+func rebuildCounters(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM stats_counters"); err != nil {
+		return err
+	}
+	for _, table := range checkedTables {
+		for _, c := range tableCounters[table] {
+			if err := recountCounter(table, c)(ctx, tx); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// recountCounter returns a repair computing a counter from scratch, for
+// every profile.
+func recountCounter(table string, c counter) func(context.Context, querier) error {
+	return func(ctx context.Context, q querier) error {
+		_, err := q.ExecContext(ctx, "DELETE FROM stats_counters WHERE table_name = ? AND counter = ?", table, c.name)
+		if err != nil {
+			return err
+		}
+		query := fmt.Sprintf(`INSERT INTO stats_counters (table_name, profile, counter, value)
+			SELECT ?, r.profile, ?, IFNULL(SUM(%s), 0) FROM %s r GROUP BY r.profile`, c.expr, table)
+		_, err = q.ExecContext(ctx, query, table, c.name)
+		return err
+	}
+}
+
+// counters returns the counters of table over the rows matching filter.
+// They are read from stats_counters when the filter only picks a profile,
+// and computed from the table otherwise.
+func (s *SQLiteStore) counters(ctx context.Context, table string, filter Filter) (map[string]int, error) {
+	values := make(map[string]int)
+	if filter.counted() {
+		query := "SELECT counter, SUM(value) FROM stats_counters WHERE table_name = ?"
+		args := []any{table}
+		if filter.Profile != "" {
+			query += " AND profile = ?"
+			args = append(args, strings.ToLower(filter.Profile))
+		}
+		err := queryRows(ctx, s.db, query+" GROUP BY counter", args, func(rows *sql.Rows) error {
+			var name string
+			var value int
+			if err := rows.Scan(&name, &value); err != nil {
+				return err
+			}
+			values[name] = value
+			return nil
+		})
+		return values, err
+	}
+	return scanCounters(ctx, s.db, table, filter)
+}
+
+// scanCounters computes the counters of table from its rows.
+func scanCounters(ctx context.Context, q querier, table string, filter Filter) (map[string]int, error) {
+	where, args := filter.where()
+	sums := make([]string, len(tableCounters[table]))
+	for i, c := range tableCounters[table] {
+		sums[i] = fmt.Sprintf("IFNULL(SUM(%s), 0)", c.expr)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s r WHERE %s", strings.Join(sums, ", "), table, where)
+
+	values := make(map[string]int)
+	err := queryRows(ctx, q, query, args, func(rows *sql.Rows) error {
+		dest := make([]any, len(tableCounters[table]))
+		ints := make([]int, len(dest))
+		for i := range dest {
+			dest[i] = &ints[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		for i, c := range tableCounters[table] {
+			values[c.name] = ints[i]
+		}
+		return nil
+	})
+	return values, err
+}
+
+// checkCounters compares the stats counters of every profile with the
+// tables they count.
+func checkCounters(ctx context.Context, q querier) ([]Problem, error) {
+	stored := make(map[string]int)
+	err := queryRows(ctx, q, "SELECT table_name || '.' || profile || '.' || counter, value FROM stats_counters", nil, func(rows *sql.Rows) error {
+		var name string
+		var value int
+		if err := rows.Scan(&name, &value); err != nil {
+			return err
+		}
+		stored[name] = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []Problem
+	for _, table := range checkedTables {
+		actual := make(map[string]int)
+		sums := make([]string, len(tableCounters[table]))
+		for i, c := range tableCounters[table] {
+			sums[i] = fmt.Sprintf("IFNULL(SUM(%s), 0)", c.expr)
+		}
+		query := fmt.Sprintf("SELECT r.profile, %s FROM %s r GROUP BY r.profile", strings.Join(sums, ", "), table)
+		err := queryRows(ctx, q, query, nil, func(rows *sql.Rows) error {
+			var profile string
+			ints := make([]int, len(tableCounters[table]))
+			dest := []any{&profile}
+			for i := range ints {
+				dest = append(dest, &ints[i])
+			}
+			if err := rows.Scan(dest...); err != nil {
+				return err
+			}
+			for i, c := range tableCounters[table] {
+				actual[table+"."+profile+"."+c.name] = ints[i]
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Counters of profiles without rows left must be 0.
+		for name := range stored {
+			if _, ok := actual[name]; !ok && strings.HasPrefix(name, table+".") {
+				actual[name] = 0
+			}
+		}
+		names := slices.Sorted(maps.Keys(actual))
+		recounted := make(map[string]bool)
+		for _, name := range names {
+			value, ok := stored[name]
+			if ok && value == actual[name] {
+				continue
+			}
+			c := counterNamed(table, name[strings.LastIndex(name, ".")+1:])
+			p := Problem{Table: "stats_counters", Fix: "recount it"}
+			if !recounted[c.name] {
+				p.repair = recountCounter(table, c)
+				recounted[c.name] = true
+			}
+			if ok {
+				p.Message = fmt.Sprintf("counter %s is %d, should be %d", name, value, actual[name])
+			} else {
+				p.Message = fmt.Sprintf("counter %s is missing", name)
+			}
+			problems = append(problems, p)
+		}
+	}
+	return problems, nil
+}
+
+// counterNamed returns the counter of table with the given name.
+func counterNamed(table, name string) counter {
+	for _, c := range tableCounters[table] {
+		if c.name == name {
+			return c
+		}
+	}
+	return counter{}
+}
@@ -0,0 +1,146 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"testing"
+)
+
+// checkCountersMatch fails the test when the counters of a table, overall
+// or for a profile, differ from the ones computed from its rows.
+func checkCountersMatch(t *testing.T, s *SQLiteStore, step string) {
+	t.Helper()
+	ctx := context.Background()
+	for _, table := range checkedTables {
+		for _, profile := range []string{"", DefaultProfile, "bob"} {
+			filter := Filter{Profile: profile}
+			counted, err := s.counters(ctx, table, filter)
+			if err != nil {
+				t.Fatalf("%s: counters: %v", step, err)
+			}
+			scanned, err := scanCounters(ctx, s.db, table, filter)
+			if err != nil {
+				t.Fatalf("%s: scanCounters: %v", step, err)
+			}
+			// Counters of profiles without rows may be missing or 0
+			for name, v := range scanned {
+				if counted[name] != v {
+					t.Errorf("%s: %s counters of %q = %v, want %v", step, table, profile, counted, scanned)
+					break
+				}
+			}
+
+			where, args := filter.where()
+			var rows int
+			query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", table, where)
+			if err := s.db.QueryRowContext(ctx, query, args...).Scan(&rows); err != nil {
+				t.Fatalf("%s: %v", step, err)
+			}
+			if counted["rows"] != rows {
+				t.Errorf("%s: %s rows counter of %q = %d, want COUNT(*) %d", step, table, profile, counted["rows"], rows)
+			}
+		}
+	}
+}
+
+func TestCountersFollowChanges(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	if err := s.AddProfile(ctx, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	bob := Meta{Profile: "bob"}
+
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"insert", func() error {
+			for _, flip := range []FlipType{HH, HT, TH, TT} {
+				if err := s.InsertFlip(ctx, flip, Meta{}); err != nil {
+					return err
+				}
+			}
+			for _, egg := range []EggType{H, HX, T, TX} {
+				if err := s.InsertExeggutor(ctx, egg, bob); err != nil {
+					return err
+				}
+			}
+			for heads := range 4 {
+				if err := s.InsertMisty(ctx, heads, Meta{}); err != nil {
+					return err
+				}
+			}
+			return s.InsertFlip(ctx, HH, bob)
+		}},
+		{"batch", func() error {
+			return s.InsertBatch(ctx,
+				Batch{Meta: bob, Flips: []FlipType{TT, HT}, Eggs: []EggType{H}, Misty: []int{5}})
+		}},
+		{"edit", func() error {
+			if err := s.UpdateFlip(ctx, 1, TT); err != nil {
+				return err
+			}
+			if err := s.UpdateEgg(ctx, 2, T); err != nil {
+				return err
+			}
+			return s.UpdateMisty(ctx, 4, 0)
+		}},
+		{"delete", func() error {
+			if err := s.Delete(ctx, Kanga, 2); err != nil {
+				return err
+			}
+			if err := s.Delete(ctx, Egg, 1); err != nil {
+				return err
+			}
+			return s.Delete(ctx, Misty, 3)
+		}},
+		{"undo", func() error {
+			if err := s.Undo(ctx, "bob"); err != nil {
+				return err
+			}
+			if err := s.UndoEgg(ctx, "bob"); err != nil {
+				return err
+			}
+			return s.UndoMisty(ctx, DefaultProfile)
+		}},
+		{"reset", func() error {
+			return s.Reset(ctx, "bob")
+		}},
+	}
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		checkCountersMatch(t, s, step.name)
+	}
+}
+
+func TestRebuildCounters(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	for _, flip := range []FlipType{HH, HT, TT} {
+		if err := s.InsertFlip(ctx, flip, Meta{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := s.counters(ctx, "flips", Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.ExecContext(ctx, "UPDATE stats_counters SET value = value + 7"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RebuildCounters(ctx); err != nil {
+		t.Fatalf("RebuildCounters: %v", err)
+	}
+	after, err := s.counters(ctx, "flips", Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(before, after) {
+		t.Errorf("counters after rebuild = %v, want %v", after, before)
+	}
+	checkCountersMatch(t, s, "rebuild")
+}
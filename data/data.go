@@ -1,15 +1,25 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	_ "modernc.org/sqlite"
 )
 
+// ErrInvalidValue is returned when asked to store a value a card can't
+// produce.
+var ErrInvalidValue = errors.New("invalid value")
+
+// ErrNotFound is returned when an entry doesn't exist.
+var ErrNotFound = errors.New("entry not found")
+
 type TableType int
 
 const (
@@ -27,6 +37,9 @@ const (
 	HT
 )
 
+// KangaDamagePerHeads is the damage Kangaskhan deals for each heads.
+const KangaDamagePerHeads = 30
+
 type Stats struct {
 	TotalFlips  int
 	DoubleHeads int
@@ -35,347 +48,372 @@ type Stats struct {
 	TotalTails  int
 }
 
-func Init() (*sql.DB, error) {
+// SQLiteStore is a Store backed by a SQLite database.
+type SQLiteStore struct {
+	db   *sql.DB
+	path string
+}
+
+// DefaultPath returns the path of the database kept next to the kanga
+// executable.
+func DefaultPath() (string, error) {
 	exePath, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(exePath), "kanga.db"), nil
+}
+
+// Init opens the database at DefaultPath.
+func Init(ctx context.Context) (*SQLiteStore, error) {
+	dbPath, err := DefaultPath()
 	if err != nil {
 		return nil, err
 	}
+	return Open(ctx, dbPath)
+}
 
-	dbPath := filepath.Join(filepath.Dir(exePath), "kanga.db")
-	db, err := sql.Open("sqlite", dbPath)
+// Open opens the SQLite database at path, creating the file and its tables
+// if needed.
+func Open(ctx context.Context, path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
 	if err != nil {
 		return nil, err
 	}
+	if err := createTables(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteStore{db: db, path: path}, nil
+}
+
+// Close closes the underlying database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
 
+func createTables(ctx context.Context, db *sql.DB) error {
 	// Enable WAL mode
-	_, err = db.Exec("PRAGMA journal_mode=WAL;")
+	_, err := db.ExecContext(ctx, "PRAGMA journal_mode=WAL;")
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	createFlipsTableSQL := `CREATE TABLE IF NOT EXISTS flips (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		heads1 INTEGER NOT NULL,
 		heads2 INTEGER NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		created_at DATETIME DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+		note TEXT NOT NULL DEFAULT '',
+		tags TEXT NOT NULL DEFAULT '',
+		profile TEXT NOT NULL DEFAULT 'default',
+		deck TEXT NOT NULL DEFAULT '',
+		opponent TEXT NOT NULL DEFAULT ''
 	);`
-	_, err = db.Exec(createFlipsTableSQL)
+	_, err = db.ExecContext(ctx, createFlipsTableSQL)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	createExeggutorTableSQL := `CREATE TABLE IF NOT EXISTS exeggutor (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		heads INTEGER NOT NULL,
 		mattered BOOLEAN DEFAULT TRUE,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		created_at DATETIME DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+		note TEXT NOT NULL DEFAULT '',
+		tags TEXT NOT NULL DEFAULT '',
+		profile TEXT NOT NULL DEFAULT 'default',
+		deck TEXT NOT NULL DEFAULT '',
+		opponent TEXT NOT NULL DEFAULT ''
 	);`
-	_, err = db.Exec(createExeggutorTableSQL)
+	_, err = db.ExecContext(ctx, createExeggutorTableSQL)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	createMistyTableSQL := `CREATE TABLE IF NOT EXISTS misty (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		heads INTEGER NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		created_at DATETIME DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+		note TEXT NOT NULL DEFAULT '',
+		tags TEXT NOT NULL DEFAULT '',
+		profile TEXT NOT NULL DEFAULT 'default',
+		deck TEXT NOT NULL DEFAULT '',
+		opponent TEXT NOT NULL DEFAULT ''
 	);`
 
-	_, err = db.Exec(createMistyTableSQL)
+	_, err = db.ExecContext(ctx, createMistyTableSQL)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Create indexes
-	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_heads1 ON flips (heads1);")
+	_, err = db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_heads1 ON flips (heads1);")
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_heads2 ON flips (heads2);")
+	_, err = db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_heads2 ON flips (heads2);")
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return db, nil
-}
-
-func HeadsInfo(db *sql.DB) (totalFlips, headsCount int, err error) {
-	var rowCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM flips").Scan(&rowCount)
+	_, err = db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_misty_heads ON misty (heads);")
 	if err != nil {
-		return
+		return err
 	}
-	totalFlips = rowCount * 2
 
-	err = db.QueryRow("SELECT IFNULL(SUM(heads1 + heads2), 0) FROM flips WHERE heads1 = 1 OR heads2 = 1").Scan(&headsCount)
-	return
-}
+	// History, duplicate checks and time based stats look rows up by time
+	for _, table := range []string{"flips", "exeggutor", "misty"} {
+		_, err = db.ExecContext(ctx, fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%[1]s_created_at ON %[1]s (created_at);", table))
+		if err != nil {
+			return err
+		}
+	}
 
-func TailsInfo(db *sql.DB) (totalFlips, tailsCount int, err error) {
-	var rowCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM flips").Scan(&rowCount)
-	if err != nil {
-		return
+	// Add the columns tables created by older versions are missing
+	for _, table := range []string{"flips", "exeggutor", "misty"} {
+		if err := addColumn(ctx, db, table, "note", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+		if err := addColumn(ctx, db, table, "tags", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
 	}
-	totalFlips = rowCount * 2
 
-	err = db.QueryRow("SELECT IFNULL(SUM((1 - heads1) + (1 - heads2)), 0) FROM flips WHERE heads1 = 0 OR heads2 = 0").Scan(&tailsCount)
-	return
+	if err := createProfiles(ctx, db); err != nil {
+		return err
+	}
+	if err := createGames(ctx, db); err != nil {
+		return err
+	}
+	if err := createDecks(ctx, db); err != nil {
+		return err
+	}
+	if err := createOpponents(ctx, db); err != nil {
+		return err
+	}
+	if err := migrate(ctx, db); err != nil {
+		return err
+	}
+	return createCounters(ctx, db)
 }
 
-func Flips(db *sql.DB) (stats Stats, err error) {
-	var rowCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM flips").Scan(&rowCount)
-	if err != nil {
-		return
-	}
-	stats.TotalFlips = rowCount * 2
+// migrations are one-off rewrites of data written by older versions. The
+// database's user_version is the number of migrations it has been through,
+// so each runs once rather than on every open.
+var migrations = []func(ctx context.Context, db *sql.DB) error{
+	normalizeTimestamps,
+}
 
-	err = db.QueryRow("SELECT IFNULL(COUNT(*), 0) FROM flips WHERE heads1 = 1 AND heads2 = 1").Scan(&stats.DoubleHeads)
-	if err != nil {
-		return
+// migrate runs the migrations the database hasn't been through yet.
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return err
 	}
-
-	err = db.QueryRow("SELECT IFNULL(COUNT(*), 0) FROM flips WHERE heads1 = 0 AND heads2 = 0").Scan(&stats.DoubleTails)
-	if err != nil {
-		return
+	for i := version; i < len(migrations); i++ {
+		if err := migrations[i](ctx, db); err != nil {
+			return fmt.Errorf("failed to migrate the database: %w", err)
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	err = db.QueryRow("SELECT IFNULL(SUM(heads1 + heads2), 0) FROM flips").Scan(&stats.TotalHeads)
+func (s *SQLiteStore) HeadsInfo(ctx context.Context, filter Filter) (totalFlips, headsCount int, err error) {
+	stats, err := s.Flips(ctx, filter)
+	return stats.TotalFlips, stats.TotalHeads, err
+}
+
+func (s *SQLiteStore) TailsInfo(ctx context.Context, filter Filter) (totalFlips, tailsCount int, err error) {
+	stats, err := s.Flips(ctx, filter)
+	return stats.TotalFlips, stats.TotalTails, err
+}
+
+func (s *SQLiteStore) Flips(ctx context.Context, filter Filter) (stats Stats, err error) {
+	c, err := s.counters(ctx, "flips", filter)
 	if err != nil {
 		return
 	}
-
-	err = db.QueryRow("SELECT IFNULL(SUM((1 - heads1) + (1 - heads2)), 0) FROM flips").Scan(&stats.TotalTails)
+	stats = Stats{
+		TotalFlips:  c["rows"] * 2,
+		DoubleHeads: c["double_heads"],
+		DoubleTails: c["double_tails"],
+		TotalHeads:  c["heads"],
+		TotalTails:  c["tails"],
+	}
 	return
 }
 
-func InsertFlip(db *sql.DB, flipType FlipType) {
-	var heads1, heads2 int
+func Damage(table TableType, heads int) int {
+	switch table {
+	case Kanga:
+		return heads * KangaDamagePerHeads
+	case Egg:
+		if heads > 0 {
+			return EggHeadsDamage
+		}
+		return EggTailsDamage
+	}
+	return 0
+}
+
+// flipHeads returns the two coins of a flip type.
+func flipHeads(flipType FlipType) (heads1, heads2 int, err error) {
 	switch flipType {
 	case TT:
-		heads1, heads2 = 0, 0
+		return 0, 0, nil
 	case HH:
-		heads1, heads2 = 1, 1
+		return 1, 1, nil
 	case TH:
-		heads1, heads2 = 0, 1
+		return 0, 1, nil
 	case HT:
-		heads1, heads2 = 1, 0
-	}
-
-	stmt := `
-	INSERT INTO flips (heads1, heads2, created_at)
-	VALUES (?, ?, CURRENT_TIMESTAMP)
-`
-	_, err := db.Exec(stmt, heads1, heads2)
-	if err != nil {
-		fmt.Printf("Failed to insert flip: %v\n", err)
+		return 1, 0, nil
 	}
+	return 0, 0, fmt.Errorf("%w: unknown flip type %d", ErrInvalidValue, flipType)
 }
 
-func Reset(db *sql.DB) {
-	_, err := db.Exec("DELETE FROM flips")
+func (s *SQLiteStore) InsertFlip(ctx context.Context, flipType FlipType, meta Meta) error {
+	heads1, heads2, err := flipHeads(flipType)
 	if err != nil {
-		fmt.Printf("Failed to reset flips table: %v\n", err)
+		return err
 	}
-	_, err = db.Exec("DELETE FROM exeggutor")
+	meta, err = meta.validate()
 	if err != nil {
-		fmt.Printf("Failed to reset exeggutor table: %v\n", err)
+		return err
 	}
 
-	_, err = db.Exec("DELETE FROM misty")
+	stmt := `
+	INSERT INTO flips (heads1, heads2, created_at, note, tags, profile, deck, opponent)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`
+	_, err = s.db.ExecContext(ctx, stmt, heads1, heads2, meta.createdAt(), meta.Note, encodeTags(meta.Tags), meta.Profile, meta.Deck, meta.Opponent)
 	if err != nil {
-		fmt.Printf("Failed to reset misty table: %v\n", err)
+		return fmt.Errorf("failed to insert flip: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Reset(ctx context.Context, profile string) error {
+	where, args := Filter{Profile: profile}.where()
+	for _, table := range []string{"flips", "exeggutor", "misty", "games"} {
+		_, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", table, where), args...)
+		if err != nil {
+			return fmt.Errorf("failed to reset %s table: %w", table, err)
+		}
 	}
+	return nil
 }
 
-func Undo(db *sql.DB) {
+func (s *SQLiteStore) Undo(ctx context.Context, profile string) error {
 	stmt := `
 	DELETE FROM flips
-	WHERE id = (SELECT MAX(id) FROM flips)
-`
-	_, err := db.Exec(stmt)
+	WHERE id = (SELECT MAX(id) FROM flips WHERE profile = ?)
+	`
+	_, err := s.db.ExecContext(ctx, stmt, profile)
 	if err != nil {
-		fmt.Printf("Failed to undo flip: %v\n", err)
+		return fmt.Errorf("failed to undo flip: %w", err)
 	}
+	return nil
 }
 
-func DumpCsv(db *sql.DB, folder string, tables map[TableType]bool) error {
+// DumpCsv writes the selected tables of a store to CSV files in folder. When
+// no table is selected, every table is written, along with the games. Every
+// row ends with the meta fields of csvMeta.
+func DumpCsv(ctx context.Context, store Store, folder string, tables map[TableType]bool) error {
 	empty := tableEmpty(tables)
 
-	for table, v := range tables {
-		if empty || v {
-			err := dumpTable(db, folder, table)
+	for _, table := range []TableType{Kanga, Egg, Misty} {
+		if empty || tables[table] {
+			err := dumpTable(ctx, store, folder, table)
 			if err != nil {
 				return err
 			}
 		}
 	}
+	if empty {
+		return dumpGames(ctx, store, folder)
+	}
 	return nil
 }
 
-func dumpTable(db *sql.DB, folder string, table TableType) error {
-	var query, filename string
+func dumpTable(ctx context.Context, store Store, folder string, table TableType) error {
+	var records [][]string
 	switch table {
 	case Kanga:
-		query = "SELECT heads1, heads2, created_at FROM flips"
-		filename = "kanga.csv"
-	case Egg:
-		query = "SELECT heads, mattered, created_at FROM exeggutor"
-		filename = "exeggutor.csv"
-	case Misty:
-		query = "SELECT heads, created_at FROM misty"
-		filename = "misty.csv"
-	}
-
-	// Create the folder if it doesn't exist
-	if err := os.MkdirAll(folder, os.ModePerm); err != nil {
-		return err
-	}
-
-	rows, err := db.Query(query)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	filePath := filepath.Join(folder, filename)
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	for rows.Next() {
-		var record []string
-		if table == Kanga {
-			var heads1, heads2 int
-			var createdAt string
-			err := rows.Scan(&heads1, &heads2, &createdAt)
-			if err != nil {
-				return err
-			}
-			record = []string{fmt.Sprintf("%d", heads1), fmt.Sprintf("%d", heads2), createdAt}
-		} else if table == Egg {
-			var heads int
-			var mattered bool
-			var createdAt string
-			err := rows.Scan(&heads, &mattered, &createdAt)
-			if err != nil {
-				return err
-			}
-			record = []string{fmt.Sprintf("%d", heads), fmt.Sprintf("%t", mattered), createdAt}
-		} else if table == Misty {
-			var heads int
-			var createdAt string
-			err := rows.Scan(&heads, &createdAt)
-			if err != nil {
-				return err
-			}
-			record = []string{fmt.Sprintf("%d", heads), createdAt}
-		}
-		err = writer.Write(record)
+		entries, err := store.FlipEntries(ctx, Filter{}, 0)
 		if err != nil {
 			return err
 		}
-	}
-
-	return rows.Err()
-}
-
-func ReadCsv(db *sql.DB, folder string, table string) error {
-	if table == "" || table == "kanga" {
-		err := readTable(db, folder, "kanga")
+		for _, e := range entries {
+			records = append(records, append([]string{fmt.Sprintf("%d", e.Heads1), fmt.Sprintf("%d", e.Heads2), e.CreatedAt}, csvMeta(e.Meta)...))
+		}
+	case Egg:
+		entries, err := store.EggEntries(ctx, Filter{}, 0)
 		if err != nil {
 			return err
 		}
-	}
-	if table == "" || table == "egg" {
-		err := readTable(db, folder, "egg")
+		for _, e := range entries {
+			records = append(records, append([]string{fmt.Sprintf("%d", e.Heads), fmt.Sprintf("%t", e.Mattered), e.CreatedAt}, csvMeta(e.Meta)...))
+		}
+	case Misty:
+		entries, err := store.MistyEntries(ctx, Filter{}, 0)
 		if err != nil {
 			return err
 		}
+		for _, e := range entries {
+			records = append(records, append([]string{fmt.Sprintf("%d", e.Heads), e.CreatedAt}, csvMeta(e.Meta)...))
+		}
 	}
-	return nil
+
+	return writeCsvFile(folder, csvFiles[table], records)
 }
 
-func readTable(db *sql.DB, folder string, table string) error {
-	var query, filename string
-	switch table {
-	case "kanga":
-		query = "INSERT INTO flips (heads1, heads2, created_at) VALUES (?, ?, ?)"
-		filename = "kanga.csv"
-	case "egg":
-		query = "INSERT INTO exeggutor (heads, mattered, created_at) VALUES (?, ?, ?)"
-		filename = "exeggutor.csv"
-	default:
-		return fmt.Errorf("unknown table: %s", table)
-	}
-
-	filePath := filepath.Join(folder, filename)
-	file, err := os.Open(filePath)
+// dumpGames writes every game to gamesCsvFile in folder. The game field of
+// their meta, which links entries to a game, is left empty.
+func dumpGames(ctx context.Context, store Store, folder string) error {
+	games, err := store.Games(ctx, Filter{})
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	records := make([][]string, len(games))
+	for i, g := range games {
+		records[i] = append([]string{fmt.Sprintf("%d", g.ID), string(g.Result), g.CreatedAt}, csvMeta(g.Meta)...)
+	}
+	return writeCsvFile(folder, gamesCsvFile, records)
+}
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		return err
+// csvMeta returns the meta fields written after the timestamp of a row:
+// note, tags, profile, game, deck and opponent.
+func csvMeta(meta Meta) []string {
+	game := ""
+	if meta.Game != 0 {
+		game = fmt.Sprintf("%d", meta.Game)
 	}
+	return []string{meta.Note, strings.Join(meta.Tags, ","), meta.Profile, game, meta.Deck, meta.Opponent}
+}
 
-	tx, err := db.Begin()
-	if err != nil {
+// writeCsvFile writes records to a file in folder, creating the folder if
+// needed.
+func writeCsvFile(folder, name string, records [][]string) error {
+	if err := os.MkdirAll(folder, os.ModePerm); err != nil {
 		return err
 	}
 
-	stmt, err := tx.Prepare(query)
+	file, err := os.Create(filepath.Join(folder, name))
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
-
-	for _, record := range records {
-		if table == "kanga" {
-			if len(record) != 3 {
-				tx.Rollback()
-				return fmt.Errorf("invalid record: %v", record)
-			}
-			heads1 := record[0]
-			heads2 := record[1]
-			createdAt := record[2]
-			_, err := stmt.Exec(heads1, heads2, createdAt)
-			if err != nil {
-				tx.Rollback()
-				return err
-			}
-		} else {
-			if len(record) != 3 {
-				tx.Rollback()
-				return fmt.Errorf("invalid record: %v", record)
-			}
-			heads := record[0]
-			mattered := record[1]
-			createdAt := record[2]
-			_, err := stmt.Exec(heads, mattered, createdAt)
-			if err != nil {
-				tx.Rollback()
-				return err
-			}
-		}
-	}
+	defer file.Close()
 
-	err = tx.Commit()
-	if err != nil {
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(records); err != nil {
 		return err
 	}
-
-	return nil
+	return file.Close()
 }
 
 func tableEmpty(table map[TableType]bool) bool {
@@ -386,3 +424,40 @@ func tableEmpty(table map[TableType]bool) bool {
 	}
 	return true
 }
+
+func (s *SQLiteStore) FlipEntries(ctx context.Context, filter Filter, limit int) ([]FlipEntry, error) {
+	where, args := filter.where()
+	rows, err := s.db.QueryContext(ctx, lastRows("SELECT id, heads1, heads2, created_at, note, tags, profile, deck, opponent, IFNULL(game_id, 0) FROM flips WHERE "+where), append(args, sqlLimit(limit))...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []FlipEntry
+	for rows.Next() {
+		var e FlipEntry
+		var tags string
+		if err := rows.Scan(&e.ID, &e.Heads1, &e.Heads2, &e.CreatedAt, &e.Note, &tags, &e.Profile, &e.Deck, &e.Opponent, &e.Game); err != nil {
+			return nil, err
+		}
+		e.Tags = decodeTags(tags)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// lastRows wraps a query so it returns its latest rows, up to a LIMIT given
+// as the final argument, in the order they happened. Entries logged with
+// the same timestamp keep the order they were logged in.
+func lastRows(query string) string {
+	return "SELECT * FROM (" + query + " ORDER BY created_at DESC, id DESC LIMIT ?) ORDER BY created_at, id"
+}
+
+// sqlLimit converts a limit where <= 0 means no limit to SQLite's LIMIT, which
+// uses -1 for that.
+func sqlLimit(limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
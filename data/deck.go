@@ -0,0 +1,147 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// NoDeck is the name selecting entries logged without a deck. It can't be
+// used as a deck name.
+const NoDeck = "none"
+
+// activeDeckKey is the config key prefix holding the deck a profile uses.
+const activeDeckKey = "active_deck."
+
+// NormalizeDeck lowercases a deck name and checks it. Names follow the rules
+// of tags and can't be NoDeck.
+func NormalizeDeck(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || strings.ContainsAny(name, ", \t\n") {
+		return "", fmt.Errorf("%w: invalid deck name %q", ErrInvalidValue, name)
+	}
+	if name == NoDeck {
+		return "", fmt.Errorf("%w: deck name %q is reserved", ErrInvalidValue, name)
+	}
+	return name, nil
+}
+
+// createDecks creates the decks table and the deck column of the tables
+// and games created by older versions. Entries without a deck have an empty
+// deck.
+func createDecks(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS decks (
+		name TEXT PRIMARY KEY,
+		created_at DATETIME DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+	);`)
+	if err != nil {
+		return err
+	}
+	for _, table := range checkedTables {
+		if err := addColumn(ctx, db, table, "deck", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	return addColumn(ctx, db, "games", "deck", "TEXT NOT NULL DEFAULT ''")
+}
+
+// Decks returns the name of every deck, in alphabetical order.
+func (s *SQLiteStore) Decks(ctx context.Context) ([]string, error) {
+	var names []string
+	err := queryRows(ctx, s.db, "SELECT name FROM decks ORDER BY name", nil, func(rows *sql.Rows) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		names = append(names, name)
+		return nil
+	})
+	return names, err
+}
+
+// AddDeck creates a deck.
+func (s *SQLiteStore) AddDeck(ctx context.Context, name string) error {
+	name, err := NormalizeDeck(name)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, "INSERT OR IGNORE INTO decks (name) VALUES (?)", name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: deck %q already exists", ErrInvalidValue, name)
+	}
+	return nil
+}
+
+// ActiveDeck returns the deck a profile uses, or "" when it uses none.
+func (s *SQLiteStore) ActiveDeck(ctx context.Context, profile string) (string, error) {
+	var name string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM config WHERE key = ?", activeDeckKey+profile).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return name, err
+}
+
+// UseDeck puts a deck in use for a profile, or stops using one when name is
+// NoDeck. It returns ErrNotFound if the deck doesn't exist.
+func (s *SQLiteStore) UseDeck(ctx context.Context, profile, name string) error {
+	if strings.ToLower(name) == NoDeck {
+		_, err := s.db.ExecContext(ctx, "DELETE FROM config WHERE key = ?", activeDeckKey+profile)
+		return err
+	}
+	name, err := NormalizeDeck(name)
+	if err != nil {
+		return err
+	}
+	if err := s.checkDeck(ctx, name); err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)", activeDeckKey+profile, name)
+	return err
+}
+
+// checkDeck returns ErrNotFound if the deck doesn't exist.
+func (s *SQLiteStore) checkDeck(ctx context.Context, name string) error {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM decks WHERE name = ?", name).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: no deck %q", ErrNotFound, name)
+	}
+	return nil
+}
+
+// checkDecks finds rows logged with a deck that doesn't exist.
+func checkDecks(ctx context.Context, q querier) ([]Problem, error) {
+	var problems []Problem
+	for _, table := range checkedTables {
+		query := fmt.Sprintf("SELECT DISTINCT deck FROM %s WHERE deck != '' AND deck NOT IN (SELECT name FROM decks) ORDER BY deck", table)
+		err := queryRows(ctx, q, query, nil, func(rows *sql.Rows) error {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			problems = append(problems, Problem{
+				Table:   table,
+				Message: fmt.Sprintf("rows logged with unknown deck %q", name),
+				Fix:     "create the deck",
+				repair: func(ctx context.Context, q querier) error {
+					_, err := q.ExecContext(ctx, "INSERT OR IGNORE INTO decks (name) VALUES (?)", name)
+					return err
+				},
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return problems, nil
+}
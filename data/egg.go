@@ -1,7 +1,7 @@
 package data
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 )
 
@@ -14,6 +14,12 @@ const (
 	TX
 )
 
+// Damage dealt by an exeggutor attack depending on the coin.
+const (
+	EggHeadsDamage = 80
+	EggTailsDamage = 40
+)
+
 type EggStats struct {
 	TotalEntries     int
 	TotalHeads       int
@@ -22,55 +28,86 @@ type EggStats struct {
 	HeadsMattered    int
 }
 
-func UndoEgg(db *sql.DB) {
+func (s *SQLiteStore) UndoEgg(ctx context.Context, profile string) error {
 	stmt := `
 	DELETE FROM exeggutor
-	WHERE id = (SELECT MAX(id) FROM exeggutor)
+	WHERE id = (SELECT MAX(id) FROM exeggutor WHERE profile = ?)
 	`
-	_, err := db.Exec(stmt)
+	_, err := s.db.ExecContext(ctx, stmt, profile)
 	if err != nil {
-		fmt.Printf("Failed to undo flip: %v\n", err)
+		return fmt.Errorf("failed to undo exeggutor entry: %w", err)
 	}
+	return nil
 }
-func InsertExeggutor(db *sql.DB, eggType EggType) {
-	var heads int
-	var mattered bool
+
+// eggValues returns the coin and mattered flag of an exeggutor type.
+func eggValues(eggType EggType) (heads int, mattered bool, err error) {
 	switch eggType {
 	case H:
-		heads = 1
-		mattered = true
+		return 1, true, nil
 	case HX:
-		heads = 1
-		mattered = false
+		return 1, false, nil
 	case T:
-		heads = 0
-		mattered = true
+		return 0, true, nil
 	case TX:
-		heads = 0
-		mattered = false
+		return 0, false, nil
+	}
+	return 0, false, fmt.Errorf("%w: unknown exeggutor type %d", ErrInvalidValue, eggType)
+}
+
+func (s *SQLiteStore) InsertExeggutor(ctx context.Context, eggType EggType, meta Meta) error {
+	heads, mattered, err := eggValues(eggType)
+	if err != nil {
+		return err
+	}
+	meta, err = meta.validate()
+	if err != nil {
+		return err
 	}
 
 	stmt := `
-	INSERT INTO exeggutor (heads, mattered, created_at)
-	VALUES (?, ?, CURRENT_TIMESTAMP)
+	INSERT INTO exeggutor (heads, mattered, created_at, note, tags, profile, deck, opponent)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 `
-	_, err := db.Exec(stmt, heads, mattered)
+	_, err = s.db.ExecContext(ctx, stmt, heads, mattered, meta.createdAt(), meta.Note, encodeTags(meta.Tags), meta.Profile, meta.Deck, meta.Opponent)
 	if err != nil {
-		fmt.Printf("Failed to insert exeggutor entry: %v\n", err)
+		return fmt.Errorf("failed to insert exeggutor entry: %w", err)
 	}
+	return nil
 }
 
-func GetEggStats(db *sql.DB) (stats EggStats, err error) {
-	err = db.QueryRow("SELECT COUNT(*), IFNULL(SUM(heads), 0), IFNULL(SUM(CASE WHEN heads = 0 THEN 1 ELSE 0 END), 0) FROM exeggutor").Scan(&stats.TotalEntries, &stats.TotalHeads, &stats.TotalTails)
+func (s *SQLiteStore) GetEggStats(ctx context.Context, filter Filter) (stats EggStats, err error) {
+	c, err := s.counters(ctx, "exeggutor", filter)
 	if err != nil {
 		return
 	}
+	stats = EggStats{
+		TotalEntries:     c["rows"],
+		TotalHeads:       c["heads"],
+		TotalTails:       c["tails"],
+		TotalNotMattered: c["not_mattered"],
+		HeadsMattered:    c["heads_mattered"],
+	}
+	return
+}
 
-	err = db.QueryRow("SELECT COUNT(*) FROM exeggutor WHERE mattered = 0").Scan(&stats.TotalNotMattered)
+func (s *SQLiteStore) EggEntries(ctx context.Context, filter Filter, limit int) ([]EggEntry, error) {
+	where, args := filter.where()
+	rows, err := s.db.QueryContext(ctx, lastRows("SELECT id, heads, mattered, created_at, note, tags, profile, deck, opponent, IFNULL(game_id, 0) FROM exeggutor WHERE "+where), append(args, sqlLimit(limit))...)
 	if err != nil {
-		return
+		return nil, err
 	}
+	defer rows.Close()
 
-	err = db.QueryRow("SELECT COUNT(*) FROM exeggutor WHERE heads = 1 AND mattered = 1").Scan(&stats.HeadsMattered)
-	return
+	var entries []EggEntry
+	for rows.Next() {
+		var e EggEntry
+		var tags string
+		if err := rows.Scan(&e.ID, &e.Heads, &e.Mattered, &e.CreatedAt, &e.Note, &tags, &e.Profile, &e.Deck, &e.Opponent, &e.Game); err != nil {
+			return nil, err
+		}
+		e.Tags = decodeTags(tags)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
 }
@@ -0,0 +1,210 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// GameResult is how a game ended.
+type GameResult string
+
+const (
+	Win  GameResult = "win"
+	Loss GameResult = "loss"
+	Draw GameResult = "draw"
+)
+
+// firstGameWindow bounds how far back the first game of a profile reaches
+// when it is closed, so entries logged before game tracking aren't all
+// linked to it.
+const firstGameWindow = 3 * time.Hour
+
+// Game is a closed game and the number of entries linked to it.
+type Game struct {
+	ID        int64
+	Result    GameResult
+	CreatedAt string
+	Meta
+	Flips, Eggs, Misty int
+}
+
+// GameLuck holds the coins flipped in a game.
+type GameLuck struct {
+	ID     int64
+	Result GameResult
+	// FlipHeads is the number of heads over Flips Kangaskhan attacks,
+	// and so on for the other cards.
+	Flips, FlipHeads  int
+	Eggs, EggHeads    int
+	Misty, MistyHeads int
+}
+
+// createGames creates the games table and the game_id column linking
+// entries to the game they were logged in.
+func createGames(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS games (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		result TEXT NOT NULL CHECK (result IN ('win', 'loss', 'draw')),
+		created_at DATETIME DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+		note TEXT NOT NULL DEFAULT '',
+		tags TEXT NOT NULL DEFAULT '',
+		profile TEXT NOT NULL DEFAULT 'default',
+		deck TEXT NOT NULL DEFAULT '',
+		opponent TEXT NOT NULL DEFAULT ''
+	);`)
+	if err != nil {
+		return err
+	}
+	for _, table := range checkedTables {
+		if err := addColumn(ctx, db, table, "game_id", "INTEGER"); err != nil {
+			return err
+		}
+		_, err = db.ExecContext(ctx, fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%[1]s_game ON %[1]s (game_id);", table))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EndGame closes the current game of meta's profile with result, linking it
+// every entry of the profile logged since the previous game and not linked
+// yet. The first game of a profile reaches back firstGameWindow at most.
+// A non-zero since overrides where the game starts. Linked entries logged
+// without an opponent get the opponent of the game.
+func (s *SQLiteStore) EndGame(ctx context.Context, result GameResult, meta Meta, since time.Time) (Game, error) {
+	switch result {
+	case Win, Loss, Draw:
+	default:
+		return Game{}, fmt.Errorf("%w: invalid game result %q", ErrInvalidValue, result)
+	}
+	meta, err := meta.validate()
+	if err != nil {
+		return Game{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Game{}, err
+	}
+	defer tx.Rollback()
+
+	if since.IsZero() {
+		var previous string
+		err := tx.QueryRowContext(ctx, "SELECT created_at FROM games WHERE profile = ? AND created_at <= ? ORDER BY created_at DESC, id DESC LIMIT 1",
+			meta.Profile, meta.createdAt()).Scan(&previous)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			since = meta.At.Add(-firstGameWindow)
+		case err != nil:
+			return Game{}, err
+		default:
+			if since, err = ParseTime(previous); err != nil {
+				return Game{}, err
+			}
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO games (result, created_at, note, tags, profile, deck, opponent) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		string(result), meta.createdAt(), meta.Note, encodeTags(meta.Tags), meta.Profile, meta.Deck, meta.Opponent)
+	if err != nil {
+		return Game{}, fmt.Errorf("failed to insert game: %w", err)
+	}
+	game := Game{Result: result, CreatedAt: meta.createdAt(), Meta: meta}
+	if game.ID, err = res.LastInsertId(); err != nil {
+		return Game{}, err
+	}
+
+	counts := map[string]*int{"flips": &game.Flips, "exeggutor": &game.Eggs, "misty": &game.Misty}
+	for _, table := range checkedTables {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET game_id = ?, opponent = IIF(opponent = '', ?, opponent)
+			WHERE game_id IS NULL AND profile = ? AND created_at >= ? AND created_at <= ?`, table),
+			game.ID, meta.Opponent, meta.Profile, FormatTime(since), meta.createdAt())
+		if err != nil {
+			return Game{}, fmt.Errorf("failed to link %s to the game: %w", table, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return Game{}, err
+		}
+		*counts[table] = int(n)
+	}
+	return game, tx.Commit()
+}
+
+// Games returns every game matching filter, in the order they ended, with
+// the number of entries linked to each.
+func (s *SQLiteStore) Games(ctx context.Context, filter Filter) ([]Game, error) {
+	where, args := filter.where()
+	query := fmt.Sprintf(`SELECT g.id, g.result, g.created_at, g.note, g.tags, g.profile, g.deck, g.opponent,
+		(SELECT COUNT(*) FROM flips WHERE game_id = g.id),
+		(SELECT COUNT(*) FROM exeggutor WHERE game_id = g.id),
+		(SELECT COUNT(*) FROM misty WHERE game_id = g.id)
+		FROM (SELECT * FROM games WHERE %s) g ORDER BY g.created_at, g.id`, where)
+	var games []Game
+	err := queryRows(ctx, s.db, query, args, func(rows *sql.Rows) error {
+		var g Game
+		var result, tags string
+		if err := rows.Scan(&g.ID, &result, &g.CreatedAt, &g.Note, &tags, &g.Profile, &g.Deck, &g.Opponent,
+			&g.Flips, &g.Eggs, &g.Misty); err != nil {
+			return err
+		}
+		g.Result = GameResult(result)
+		g.Tags = decodeTags(tags)
+		games = append(games, g)
+		return nil
+	})
+	return games, err
+}
+
+// GameLuck returns the coins flipped in every game matching filter, in the
+// order the games ended. The filter applies to the games, not the entries.
+func (s *SQLiteStore) GameLuck(ctx context.Context, filter Filter) ([]GameLuck, error) {
+	where, args := filter.where()
+	query := fmt.Sprintf(`SELECT g.id, g.result,
+		(SELECT COUNT(*) FROM flips WHERE game_id = g.id), (SELECT IFNULL(SUM(heads1 + heads2), 0) FROM flips WHERE game_id = g.id),
+		(SELECT COUNT(*) FROM exeggutor WHERE game_id = g.id), (SELECT IFNULL(SUM(heads), 0) FROM exeggutor WHERE game_id = g.id),
+		(SELECT COUNT(*) FROM misty WHERE game_id = g.id), (SELECT IFNULL(SUM(heads), 0) FROM misty WHERE game_id = g.id)
+		FROM (SELECT * FROM games WHERE %s) g ORDER BY g.created_at, g.id`, where)
+	var games []GameLuck
+	err := queryRows(ctx, s.db, query, args, func(rows *sql.Rows) error {
+		var g GameLuck
+		var result string
+		if err := rows.Scan(&g.ID, &result, &g.Flips, &g.FlipHeads, &g.Eggs, &g.EggHeads, &g.Misty, &g.MistyHeads); err != nil {
+			return err
+		}
+		g.Result = GameResult(result)
+		games = append(games, g)
+		return nil
+	})
+	return games, err
+}
+
+// checkGames finds rows linked to a game that doesn't exist.
+func checkGames(ctx context.Context, q querier) ([]Problem, error) {
+	var problems []Problem
+	for _, table := range checkedTables {
+		query := fmt.Sprintf("SELECT id, game_id FROM %s WHERE game_id IS NOT NULL AND game_id NOT IN (SELECT id FROM games) ORDER BY id", table)
+		err := queryRows(ctx, q, query, nil, func(rows *sql.Rows) error {
+			var id, game int64
+			if err := rows.Scan(&id, &game); err != nil {
+				return err
+			}
+			problems = append(problems, Problem{
+				Table:   table,
+				ID:      id,
+				Message: fmt.Sprintf("linked to unknown game #%d", game),
+				Fix:     "unlink it",
+				repair:  updateColumn(table, "game_id", id, nil),
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,94 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// tableNames maps each card to its SQLite table.
+var tableNames = map[TableType]string{
+	Kanga: "flips",
+	Egg:   "exeggutor",
+	Misty: "misty",
+}
+
+func notFound(err error, id int64) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %d", ErrNotFound, id)
+	}
+	return err
+}
+
+func (s *SQLiteStore) GetFlip(ctx context.Context, id int64) (e FlipEntry, err error) {
+	var tags string
+	err = s.db.QueryRowContext(ctx, "SELECT id, heads1, heads2, created_at, note, tags, profile, deck, opponent, IFNULL(game_id, 0) FROM flips WHERE id = ?", id).
+		Scan(&e.ID, &e.Heads1, &e.Heads2, &e.CreatedAt, &e.Note, &tags, &e.Profile, &e.Deck, &e.Opponent, &e.Game)
+	e.Tags = decodeTags(tags)
+	return e, notFound(err, id)
+}
+
+func (s *SQLiteStore) GetEgg(ctx context.Context, id int64) (e EggEntry, err error) {
+	var tags string
+	err = s.db.QueryRowContext(ctx, "SELECT id, heads, mattered, created_at, note, tags, profile, deck, opponent, IFNULL(game_id, 0) FROM exeggutor WHERE id = ?", id).
+		Scan(&e.ID, &e.Heads, &e.Mattered, &e.CreatedAt, &e.Note, &tags, &e.Profile, &e.Deck, &e.Opponent, &e.Game)
+	e.Tags = decodeTags(tags)
+	return e, notFound(err, id)
+}
+
+func (s *SQLiteStore) GetMisty(ctx context.Context, id int64) (e MistyEntry, err error) {
+	var tags string
+	err = s.db.QueryRowContext(ctx, "SELECT id, heads, created_at, note, tags, profile, deck, opponent, IFNULL(game_id, 0) FROM misty WHERE id = ?", id).
+		Scan(&e.ID, &e.Heads, &e.CreatedAt, &e.Note, &tags, &e.Profile, &e.Deck, &e.Opponent, &e.Game)
+	e.Tags = decodeTags(tags)
+	return e, notFound(err, id)
+}
+
+func (s *SQLiteStore) UpdateFlip(ctx context.Context, id int64, flipType FlipType) error {
+	heads1, heads2, err := flipHeads(flipType)
+	if err != nil {
+		return err
+	}
+	return s.execOne(ctx, id, "UPDATE flips SET heads1 = ?, heads2 = ? WHERE id = ?", heads1, heads2, id)
+}
+
+func (s *SQLiteStore) UpdateEgg(ctx context.Context, id int64, eggType EggType) error {
+	heads, mattered, err := eggValues(eggType)
+	if err != nil {
+		return err
+	}
+	return s.execOne(ctx, id, "UPDATE exeggutor SET heads = ?, mattered = ? WHERE id = ?", heads, mattered, id)
+}
+
+func (s *SQLiteStore) UpdateMisty(ctx context.Context, id int64, heads int) error {
+	if err := validateMisty(heads); err != nil {
+		return err
+	}
+	return s.execOne(ctx, id, "UPDATE misty SET heads = ? WHERE id = ?", heads, id)
+}
+
+func (s *SQLiteStore) Delete(ctx context.Context, table TableType, id int64) error {
+	name, ok := tableNames[table]
+	if !ok {
+		return fmt.Errorf("unknown table: %d", table)
+	}
+	return s.execOne(ctx, id, "DELETE FROM "+name+" WHERE id = ?", id)
+}
+
+// execOne runs a statement meant to change the entry with the given id and
+// returns ErrNotFound when it changed nothing.
+func (s *SQLiteStore) execOne(ctx context.Context, id int64, query string, args ...any) error {
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %d", ErrNotFound, id)
+	}
+	return nil
+}
@@ -0,0 +1,447 @@
+package data
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// csvFiles are the files DumpCsv writes and ReadCsv reads, per table.
+var csvFiles = map[TableType]string{
+	Kanga: "kanga.csv",
+	Egg:   "exeggutor.csv",
+	Misty: "misty.csv",
+}
+
+// gamesCsvFile is the file games are written to and read from.
+const gamesCsvFile = "games.csv"
+
+// csvMetaFields is the number of meta fields that follow the timestamp of a
+// row: note, tags, profile, game, deck and opponent. Rows written by older
+// versions have fewer of them, or stop at the timestamp.
+const csvMetaFields = 6
+
+// csvTable describes the CSV layout of a table: its result fields, then the
+// created_at timestamp, then the meta fields.
+type csvTable struct {
+	table   string
+	columns []string
+	// parse validates the result fields and returns the values to insert.
+	parse func(fields []string) ([]any, error)
+}
+
+var csvTables = map[TableType]csvTable{
+	Kanga: {
+		table:   "flips",
+		columns: []string{"heads1", "heads2"},
+		parse: func(fields []string) ([]any, error) {
+			heads1, err := parseCsvHeads("heads1", fields[0])
+			if err != nil {
+				return nil, err
+			}
+			heads2, err := parseCsvHeads("heads2", fields[1])
+			if err != nil {
+				return nil, err
+			}
+			return []any{heads1, heads2}, nil
+		},
+	},
+	Egg: {
+		table:   "exeggutor",
+		columns: []string{"heads", "mattered"},
+		parse: func(fields []string) ([]any, error) {
+			heads, err := parseCsvHeads("heads", fields[0])
+			if err != nil {
+				return nil, err
+			}
+			mattered, err := strconv.ParseBool(strings.TrimSpace(fields[1]))
+			if err != nil {
+				return nil, fmt.Errorf("mattered must be true or false, got %q", fields[1])
+			}
+			return []any{heads, mattered}, nil
+		},
+	},
+	Misty: {
+		table:   "misty",
+		columns: []string{"heads"},
+		parse: func(fields []string) ([]any, error) {
+			heads, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+			if err != nil || heads < 0 {
+				return nil, fmt.Errorf("heads must be a whole number of 0 or more, got %q", fields[0])
+			}
+			return []any{heads}, nil
+		},
+	},
+}
+
+// insert returns the statement inserting a row of the table.
+func (t csvTable) insert() string {
+	columns := append(slices.Clone(t.columns), "created_at", "note", "tags", "profile", "game_id", "deck", "opponent")
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", t.table, strings.Join(columns, ", "),
+		strings.Repeat("?, ", len(columns)-1)+"?")
+}
+
+func parseCsvHeads(column, s string) (int, error) {
+	heads, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || (heads != 0 && heads != 1) {
+		return 0, fmt.Errorf("%s must be 0 or 1, got %q", column, s)
+	}
+	return heads, nil
+}
+
+// RowError is a CSV row ReadCsv rejected.
+type RowError struct {
+	File   string
+	Line   int
+	Reason string
+	Record []string
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Reason)
+}
+
+// ImportError lists every invalid row of an import. Nothing was imported.
+type ImportError struct {
+	Rows []RowError
+}
+
+func (e *ImportError) Error() string {
+	lines := []string{fmt.Sprintf("%d invalid rows", len(e.Rows))}
+	for _, row := range e.Rows {
+		lines = append(lines, "  "+row.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (e *ImportError) Unwrap() error {
+	return ErrInvalidValue
+}
+
+// ImportResult sums up what ReadCsv imported.
+type ImportResult struct {
+	Imported map[TableType]int
+	// Rejected lists the invalid rows that were skipped.
+	Rejected []RowError
+	// RejectFiles lists the files the skipped rows were written to.
+	RejectFiles []string
+	// Games is the number of games imported.
+	Games int
+}
+
+// csvRow is a validated row, ready to insert.
+type csvRow struct {
+	table  TableType
+	values []any
+	meta   Meta
+}
+
+// csvGame is a validated row of gamesCsvFile. Its id is the one it was
+// dumped with, which the entries linked to it refer to.
+type csvGame struct {
+	id        int64
+	result    GameResult
+	createdAt string
+	meta      Meta
+}
+
+// ReadCsv imports the CSV files DumpCsv writes from folder. When no table is
+// selected, every file present is read. Every row is validated first: if
+// any is invalid, nothing is imported and an *ImportError lists them all.
+// With skipInvalid, the valid rows are imported instead, and the invalid
+// ones are written next to their file, e.g. to kanga.rejects.csv. Rows keep
+// the profile, deck and opponent they were dumped with. Rows without a profile, such
+// as those written by older versions, are imported for profile, or
+// DefaultProfile when it is empty. When no table is selected, the games in gamesCsvFile are imported
+// too, and entries linked to one of them are linked to its import.
+func (s *SQLiteStore) ReadCsv(ctx context.Context, folder string, tables map[TableType]bool, skipInvalid bool, profile string) (ImportResult, error) {
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	profile, err := NormalizeProfile(profile)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	empty := tableEmpty(tables)
+	result := ImportResult{Imported: make(map[TableType]int)}
+
+	var rows []csvRow
+	var games []csvGame
+	rejected := make(map[string][]RowError)
+	var files []string
+	addFile := func(path string, invalid []RowError) {
+		files = append(files, path)
+		if len(invalid) > 0 {
+			rejected[path] = invalid
+			result.Rejected = append(result.Rejected, invalid...)
+		}
+	}
+	for _, table := range []TableType{Kanga, Egg, Misty} {
+		if !empty && !tables[table] {
+			continue
+		}
+		path := filepath.Join(folder, csvFiles[table])
+		valid, invalid, err := readCsvFile(path, func(record []string) (csvRow, error) {
+			return parseCsvRecord(table, record, profile)
+		})
+		if empty && errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		rows = append(rows, valid...)
+		addFile(path, invalid)
+	}
+	if empty {
+		path := filepath.Join(folder, gamesCsvFile)
+		valid, invalid, err := readCsvFile(path, func(record []string) (csvGame, error) {
+			return parseCsvGame(record, profile)
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return result, err
+		}
+		if err == nil {
+			games = valid
+			addFile(path, invalid)
+		}
+	}
+	if len(files) == 0 {
+		return result, fmt.Errorf("no CSV files found in %s", folder)
+	}
+	if len(result.Rejected) > 0 && !skipInvalid {
+		return result, &ImportError{Rows: result.Rejected}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return result, err
+	}
+	defer tx.Rollback()
+	addName := func(table, name string) error {
+		if name == "" {
+			return nil
+		}
+		_, err := tx.ExecContext(ctx, fmt.Sprintf("INSERT OR IGNORE INTO %s (name) VALUES (?)", table), name)
+		return err
+	}
+	addNames := func(meta Meta) error {
+		if err := addName("profiles", meta.Profile); err != nil {
+			return err
+		}
+		return addName("decks", meta.Deck)
+	}
+
+	// Games get new ids, which the entries linked to them are imported with.
+	gameIDs := make(map[int64]int64)
+	for _, g := range games {
+		if _, ok := gameIDs[g.id]; ok {
+			continue
+		}
+		if err := addNames(g.meta); err != nil {
+			return result, err
+		}
+		res, err := tx.ExecContext(ctx, "INSERT INTO games (result, created_at, note, tags, profile, deck, opponent) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			g.result, g.createdAt, g.meta.Note, encodeTags(g.meta.Tags), g.meta.Profile, g.meta.Deck, g.meta.Opponent)
+		if err != nil {
+			return result, err
+		}
+		if gameIDs[g.id], err = res.LastInsertId(); err != nil {
+			return result, err
+		}
+	}
+	for _, row := range rows {
+		if err := addNames(row.meta); err != nil {
+			return result, err
+		}
+		var game any
+		if id, ok := gameIDs[row.meta.Game]; ok {
+			game = id
+		}
+		values := append(row.values, row.meta.Note, encodeTags(row.meta.Tags), row.meta.Profile, game, row.meta.Deck, row.meta.Opponent)
+		if _, err := tx.ExecContext(ctx, csvTables[row.table].insert(), values...); err != nil {
+			return result, err
+		}
+		result.Imported[row.table]++
+	}
+	if err := tx.Commit(); err != nil {
+		return result, err
+	}
+	result.Games = len(gameIDs)
+
+	for _, path := range files {
+		if len(rejected[path]) == 0 {
+			continue
+		}
+		rejectPath := strings.TrimSuffix(path, ".csv") + ".rejects.csv"
+		if err := writeRejects(rejectPath, rejected[path]); err != nil {
+			return result, err
+		}
+		result.RejectFiles = append(result.RejectFiles, rejectPath)
+	}
+	return result, nil
+}
+
+// readCsvFile reads every row of a CSV file and validates it with parse.
+func readCsvFile[T any](path string, parse func(record []string) (T, error)) (valid []T, invalid []RowError, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return valid, invalid, nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			invalid = append(invalid, RowError{File: path, Line: parseErr.StartLine, Reason: parseErr.Err.Error(), Record: record})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		row, err := parse(record)
+		if err != nil {
+			invalid = append(invalid, RowError{File: path, Line: line, Reason: err.Error(), Record: record})
+			continue
+		}
+		valid = append(valid, row)
+	}
+}
+
+// parseCsvRecord parses a row of a table, with all, some or none of the
+// meta fields. Rows without a profile are read as profile's.
+func parseCsvRecord(table TableType, record []string, profile string) (csvRow, error) {
+	layout := csvTables[table]
+	fields := len(layout.columns) + 1
+	if len(record) < fields || len(record) > fields+csvMetaFields {
+		return csvRow{}, fmt.Errorf("expected %d to %d fields, got %d", fields, fields+csvMetaFields, len(record))
+	}
+	values, err := layout.parse(record[:fields-1])
+	if err != nil {
+		return csvRow{}, err
+	}
+	createdAt, err := parseCsvTime(record[fields-1])
+	if err != nil {
+		return csvRow{}, err
+	}
+	meta, err := parseCsvMeta(record[fields:], profile)
+	if err != nil {
+		return csvRow{}, err
+	}
+	return csvRow{table, append(values, createdAt), meta}, nil
+}
+
+// parseCsvGame parses a row of gamesCsvFile: id, result and timestamp, then
+// the meta fields. The game field of its meta is ignored.
+func parseCsvGame(record []string, profile string) (csvGame, error) {
+	const fields = 3
+	if len(record) < fields || len(record) > fields+csvMetaFields {
+		return csvGame{}, fmt.Errorf("expected %d to %d fields, got %d", fields, fields+csvMetaFields, len(record))
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(record[0]), 10, 64)
+	if err != nil || id <= 0 {
+		return csvGame{}, fmt.Errorf("id must be a whole number above 0, got %q", record[0])
+	}
+	result := GameResult(strings.ToLower(strings.TrimSpace(record[1])))
+	switch result {
+	case Win, Loss, Draw:
+	default:
+		return csvGame{}, fmt.Errorf("result must be win, loss or draw, got %q", record[1])
+	}
+	createdAt, err := parseCsvTime(record[2])
+	if err != nil {
+		return csvGame{}, err
+	}
+	meta, err := parseCsvMeta(record[fields:], profile)
+	if err != nil {
+		return csvGame{}, err
+	}
+	meta.Game = 0
+	return csvGame{id, result, createdAt, meta}, nil
+}
+
+func parseCsvTime(s string) (string, error) {
+	t, err := ParseTime(strings.TrimSpace(s))
+	if err != nil {
+		return "", fmt.Errorf("invalid timestamp %q", s)
+	}
+	if err := validateTime(t); err != nil {
+		return "", err
+	}
+	return FormatTime(t), nil
+}
+
+// parseCsvMeta parses the meta fields of a row: note, tags, profile, game,
+// deck and opponent.
+// Missing fields are read as blank, and a blank profile as profile.
+func parseCsvMeta(fields []string, profile string) (Meta, error) {
+	fields = append(slices.Clone(fields), make([]string, csvMetaFields-len(fields))...)
+	meta := Meta{Note: fields[0], Profile: profile}
+	var err error
+	if tags := strings.TrimSpace(fields[1]); tags != "" {
+		if meta.Tags, err = NormalizeTags(strings.Split(tags, ",")); err != nil {
+			return Meta{}, err
+		}
+	}
+	if name := strings.TrimSpace(fields[2]); name != "" {
+		if meta.Profile, err = NormalizeProfile(name); err != nil {
+			return Meta{}, err
+		}
+	}
+	if game := strings.TrimSpace(fields[3]); game != "" {
+		if meta.Game, err = strconv.ParseInt(game, 10, 64); err != nil || meta.Game <= 0 {
+			return Meta{}, fmt.Errorf("game must be empty or a whole number above 0, got %q", fields[3])
+		}
+	}
+	if name := strings.TrimSpace(fields[4]); name != "" {
+		if meta.Deck, err = NormalizeDeck(name); err != nil {
+			return Meta{}, err
+		}
+	}
+	if name := strings.TrimSpace(fields[5]); name != "" {
+		if meta.Opponent, err = NormalizeOpponent(name); err != nil {
+			return Meta{}, err
+		}
+	}
+	return meta, nil
+}
+
+// writeRejects writes rejected rows as they were read, each followed by the
+// reason it was rejected.
+func writeRejects(path string, rows []RowError) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	for _, row := range rows {
+		record := append(append([]string(nil), row.Record...), fmt.Sprintf("line %d: %s", row.Line, row.Reason))
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,148 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCsvRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src := openTestStore(t)
+	if err := src.AddProfile(ctx, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.AddDeck(ctx, "haymaker"); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	meta := Meta{Note: "first, game", Tags: []string{"x", "y"}, At: at, Profile: "bob", Deck: "haymaker", Opponent: "mewtwo"}
+	err := src.InsertBatch(ctx, Batch{Flips: []FlipType{HH}, Eggs: []EggType{HX}, Misty: []int{2}, Meta: meta})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.EndGame(ctx, Win, Meta{Profile: "bob", At: at.Add(time.Minute)}, at.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.InsertFlip(ctx, TT, Meta{At: at.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+
+	folder := t.TempDir()
+	if err := DumpCsv(ctx, src, folder, nil); err != nil {
+		t.Fatalf("DumpCsv: %v", err)
+	}
+	dst := openTestStore(t)
+	result, err := dst.ReadCsv(ctx, folder, nil, false, "")
+	if err != nil {
+		t.Fatalf("ReadCsv: %v", err)
+	}
+	if result.Imported[Kanga] != 2 || result.Imported[Egg] != 1 || result.Imported[Misty] != 1 || result.Games != 1 {
+		t.Errorf("ReadCsv imported %v and %d games, want 2 flips, 1 of the others and 1 game", result.Imported, result.Games)
+	}
+
+	srcFlips, err := src.FlipEntries(ctx, Filter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstFlips, err := dst.FlipEntries(ctx, Filter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(srcFlips, dstFlips) {
+		t.Errorf("flips after the round trip = %+v, want %+v", dstFlips, srcFlips)
+	}
+	srcGames, err := src.Games(ctx, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstGames, err := dst.Games(ctx, Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dstGames) != 1 || dstGames[0].Flips != 1 || dstGames[0].Eggs != 1 || dstGames[0].Misty != 1 {
+		t.Errorf("games after the round trip = %+v, want a game linked to one entry of every card", dstGames)
+	}
+	if !reflect.DeepEqual(srcGames, dstGames) {
+		t.Errorf("games after the round trip = %+v, want %+v", dstGames, srcGames)
+	}
+	if decks, err := dst.Decks(ctx); err != nil || !reflect.DeepEqual(decks, []string{"haymaker"}) {
+		t.Errorf("decks after the round trip = %v, %v, want [haymaker]", decks, err)
+	}
+	checkCountersMatch(t, dst, "read-csv")
+}
+
+func TestReadCsvLayouts(t *testing.T) {
+	ctx := context.Background()
+	folder := t.TempDir()
+	// A row without meta, as older versions wrote them, rows with some or
+	// all of it, and a row linked to a game missing from games.csv.
+	kanga := "1,0,2026-01-02T03:04:05Z\n" +
+		"0,1,2026-01-02T03:05:00Z,a note\n" +
+		"1,1,2026-01-02T03:06:00Z,,\"Foo,bar\",bob\n" +
+		"0,0,2026-01-02T03:07:00Z,,,,7\n"
+	if err := os.WriteFile(filepath.Join(folder, "kanga.csv"), []byte(kanga), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := openTestStore(t)
+	if err := s.AddProfile(ctx, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ReadCsv(ctx, folder, nil, false, "alice"); err != nil {
+		t.Fatalf("ReadCsv: %v", err)
+	}
+	flips, err := s.FlipEntries(ctx, Filter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Meta{{Profile: "alice"}, {Note: "a note", Profile: "alice"}, {Tags: []string{"foo", "bar"}, Profile: "bob"}, {Profile: "alice"}}
+	if len(flips) != len(want) {
+		t.Fatalf("got %d flips, want %d", len(flips), len(want))
+	}
+	for i, w := range want {
+		if got := flips[i].Meta; got.Note != w.Note || !reflect.DeepEqual(got.Tags, w.Tags) || got.Profile != w.Profile || got.Game != 0 {
+			t.Errorf("flip %d has meta %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestReadCsvRejects(t *testing.T) {
+	ctx := context.Background()
+	folder := t.TempDir()
+	kanga := "1,0,2026-01-02T03:04:05Z\n" +
+		"2,0,2026-01-02T03:04:05Z\n" +
+		"1,0,2026-01-02T03:04:05Z,,bad tag\n" +
+		"1,0\n"
+	if err := os.WriteFile(filepath.Join(folder, "kanga.csv"), []byte(kanga), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := openTestStore(t)
+	_, err := s.ReadCsv(ctx, folder, nil, false, "")
+	var importErr *ImportError
+	if !errors.As(err, &importErr) || !errors.Is(err, ErrInvalidValue) {
+		t.Fatalf("ReadCsv = %v, want an *ImportError", err)
+	}
+	var lines []int
+	for _, row := range importErr.Rows {
+		lines = append(lines, row.Line)
+	}
+	if !reflect.DeepEqual(lines, []int{2, 3, 4}) {
+		t.Errorf("rejected lines = %v, want [2 3 4]", lines)
+	}
+	if stats, _ := s.Flips(ctx, Filter{}); stats.TotalFlips != 0 {
+		t.Errorf("%d coins imported despite invalid rows, want 0", stats.TotalFlips)
+	}
+
+	result, err := s.ReadCsv(ctx, folder, nil, true, "")
+	if err != nil {
+		t.Fatalf("ReadCsv with skipInvalid: %v", err)
+	}
+	if result.Imported[Kanga] != 1 || len(result.Rejected) != 3 || len(result.RejectFiles) != 1 {
+		t.Errorf("ReadCsv with skipInvalid = %+v, want 1 flip imported and 3 rows rejected to a file", result)
+	}
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Matchup holds the attacks and games played against an opponent.
+type Matchup struct {
+	// Opponent is the archetype played against, or "" for entries and
+	// games logged without one.
+	Opponent string
+	// FlipHeads is the number of heads over Flips Kangaskhan attacks, and
+	// so on for Exeggutor.
+	Flips, FlipHeads int
+	Eggs, EggHeads   int
+	// Misty is the number of Misty attempts, for MistyHeads energy.
+	Misty, MistyHeads   int
+	Wins, Losses, Draws int
+}
+
+// Games returns the number of games played in the matchup.
+func (m Matchup) Games() int {
+	return m.Wins + m.Losses + m.Draws
+}
+
+// NormalizeOpponent lowercases an opponent archetype and checks it.
+// Archetypes follow the rules of tags.
+func NormalizeOpponent(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || strings.ContainsAny(name, ", \t\n") {
+		return "", fmt.Errorf("%w: invalid opponent %q", ErrInvalidValue, name)
+	}
+	return name, nil
+}
+
+// createOpponents creates the opponent column of the tables and games
+// created by older versions.
+func createOpponents(ctx context.Context, db *sql.DB) error {
+	for _, table := range checkedTables {
+		if err := addColumn(ctx, db, table, "opponent", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+	}
+	return addColumn(ctx, db, "games", "opponent", "TEXT NOT NULL DEFAULT ''")
+}
+
+// Matchups returns the attacks and games matching filter grouped by
+// opponent, in alphabetical order, with entries logged without an opponent
+// first. The filter applies to entries and games alike.
+func (s *SQLiteStore) Matchups(ctx context.Context, filter Filter) ([]Matchup, error) {
+	where, args := filter.where()
+	byOpponent := make(map[string]*Matchup)
+	var order []string
+	get := func(opponent string) *Matchup {
+		if m, ok := byOpponent[opponent]; ok {
+			return m
+		}
+		m := &Matchup{Opponent: opponent}
+		byOpponent[opponent] = m
+		order = append(order, opponent)
+		return m
+	}
+
+	queries := []struct {
+		query string
+		set   func(m *Matchup, counts [3]int)
+	}{
+		{"SELECT opponent, COUNT(*), IFNULL(SUM(heads1 + heads2), 0), 0 FROM flips WHERE %s GROUP BY opponent",
+			func(m *Matchup, c [3]int) { m.Flips, m.FlipHeads = c[0], c[1] }},
+		{"SELECT opponent, COUNT(*), IFNULL(SUM(heads), 0), 0 FROM exeggutor WHERE %s GROUP BY opponent",
+			func(m *Matchup, c [3]int) { m.Eggs, m.EggHeads = c[0], c[1] }},
+		{"SELECT opponent, COUNT(*), IFNULL(SUM(heads), 0), 0 FROM misty WHERE %s GROUP BY opponent",
+			func(m *Matchup, c [3]int) { m.Misty, m.MistyHeads = c[0], c[1] }},
+		{"SELECT opponent, SUM(result = 'win'), SUM(result = 'loss'), SUM(result = 'draw') FROM games WHERE %s GROUP BY opponent",
+			func(m *Matchup, c [3]int) { m.Wins, m.Losses, m.Draws = c[0], c[1], c[2] }},
+	}
+	for _, q := range queries {
+		err := queryRows(ctx, s.db, fmt.Sprintf(q.query, where), args, func(rows *sql.Rows) error {
+			var opponent string
+			var counts [3]int
+			if err := rows.Scan(&opponent, &counts[0], &counts[1], &counts[2]); err != nil {
+				return err
+			}
+			q.set(get(opponent), counts)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	slices.Sort(order)
+	matchups := make([]Matchup, len(order))
+	for i, opponent := range order {
+		matchups[i] = *byOpponent[opponent]
+	}
+	return matchups, nil
+}
@@ -0,0 +1,45 @@
+package data
+
+import (
+	"context"
+	"fmt"
+)
+
+// MatteredStats splits a card's flips by whether the result mattered.
+type MatteredStats struct {
+	Mattered         int
+	HeadsMattered    int
+	NotMattered      int
+	HeadsNotMattered int
+}
+
+// matteredTables maps every card whose table records a mattered flag to
+// that table.
+var matteredTables = map[TableType]string{
+	Egg: "exeggutor",
+}
+
+// HasMattered reports whether the card records if a flip mattered.
+func HasMattered(table TableType) bool {
+	_, ok := matteredTables[table]
+	return ok
+}
+
+// GetMatteredStats counts heads separately for the flips that mattered and
+// the ones that didn't.
+func (s *SQLiteStore) GetMatteredStats(ctx context.Context, table TableType, filter Filter) (stats MatteredStats, err error) {
+	name, ok := matteredTables[table]
+	if !ok {
+		err = fmt.Errorf("table does not record whether flips mattered")
+		return
+	}
+	where, args := filter.where()
+	query := fmt.Sprintf(`SELECT
+		IFNULL(SUM(CASE WHEN mattered = 1 THEN 1 ELSE 0 END), 0),
+		IFNULL(SUM(CASE WHEN mattered = 1 AND heads = 1 THEN 1 ELSE 0 END), 0),
+		IFNULL(SUM(CASE WHEN mattered = 0 THEN 1 ELSE 0 END), 0),
+		IFNULL(SUM(CASE WHEN mattered = 0 AND heads = 1 THEN 1 ELSE 0 END), 0)
+	FROM %s WHERE %s`, name, where)
+	err = s.db.QueryRowContext(ctx, query, args...).Scan(&stats.Mattered, &stats.HeadsMattered, &stats.NotMattered, &stats.HeadsNotMattered)
+	return
+}
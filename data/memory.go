@@ -0,0 +1,831 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	_ Store = (*SQLiteStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
+
+// MemoryStore is a Store that keeps every entry in memory. It is safe for
+// concurrent use.
+type MemoryStore struct {
+	mu sync.Mutex
+	// nextIDs holds the id of the next entry of every table. Like the
+	// tables of a SQLiteStore, each table numbers its entries on its own.
+	nextIDs map[TableType]int64
+	flips   []FlipEntry
+	eggs    []EggEntry
+	misty   []MistyEntry
+
+	// profiles and decks are kept in alphabetical order.
+	profiles      []string
+	activeProfile string
+	decks         []string
+	// activeDecks maps a profile to the deck it uses.
+	activeDecks map[string]string
+	nextGameID  int64
+	games       []Game
+}
+
+// NewMemoryStore returns an empty MemoryStore, using the default profile.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		nextIDs:       map[TableType]int64{Kanga: 1, Egg: 1, Misty: 1},
+		profiles:      []string{DefaultProfile},
+		activeProfile: DefaultProfile,
+		activeDecks:   make(map[string]string),
+		nextGameID:    1,
+	}
+}
+
+// newEntry returns the id and created_at of an entry of table logged with
+// meta.
+func (m *MemoryStore) newEntry(table TableType, meta Meta) (int64, string) {
+	id := m.nextIDs[table]
+	m.nextIDs[table]++
+	return id, meta.createdAt()
+}
+
+// cloneMeta returns meta with a copy of its tags, so that entries handed
+// out can't change the stored ones.
+func cloneMeta(meta Meta) Meta {
+	meta.Tags = slices.Clone(meta.Tags)
+	return meta
+}
+
+func (m *MemoryStore) InsertFlip(ctx context.Context, flipType FlipType, meta Meta) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	heads1, heads2, err := flipHeads(flipType)
+	if err != nil {
+		return err
+	}
+	meta, err = meta.validate()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, now := m.newEntry(Kanga, meta)
+	m.flips = append(m.flips, FlipEntry{ID: id, Heads1: heads1, Heads2: heads2, CreatedAt: now, Meta: meta})
+	return nil
+}
+
+func (m *MemoryStore) InsertExeggutor(ctx context.Context, eggType EggType, meta Meta) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	heads, mattered, err := eggValues(eggType)
+	if err != nil {
+		return err
+	}
+	meta, err = meta.validate()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, now := m.newEntry(Egg, meta)
+	m.eggs = append(m.eggs, EggEntry{ID: id, Heads: heads, Mattered: mattered, CreatedAt: now, Meta: meta})
+	return nil
+}
+
+func (m *MemoryStore) InsertMisty(ctx context.Context, heads int, meta Meta) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := validateMisty(heads); err != nil {
+		return err
+	}
+	meta, err := meta.validate()
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, now := m.newEntry(Misty, meta)
+	m.misty = append(m.misty, MistyEntry{ID: id, Heads: heads, CreatedAt: now, Meta: meta})
+	return nil
+}
+
+func (m *MemoryStore) InsertBatch(ctx context.Context, batches ...Batch) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	batches, err := validateBatches(batches)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, batch := range batches {
+		for _, flipType := range batch.Flips {
+			heads1, heads2, _ := flipHeads(flipType)
+			id, now := m.newEntry(Kanga, batch.Meta)
+			m.flips = append(m.flips, FlipEntry{ID: id, Heads1: heads1, Heads2: heads2, CreatedAt: now, Meta: batch.Meta})
+		}
+		for _, eggType := range batch.Eggs {
+			heads, mattered, _ := eggValues(eggType)
+			id, now := m.newEntry(Egg, batch.Meta)
+			m.eggs = append(m.eggs, EggEntry{ID: id, Heads: heads, Mattered: mattered, CreatedAt: now, Meta: batch.Meta})
+		}
+		for _, heads := range batch.Misty {
+			id, now := m.newEntry(Misty, batch.Meta)
+			m.misty = append(m.misty, MistyEntry{ID: id, Heads: heads, CreatedAt: now, Meta: batch.Meta})
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Undo(ctx context.Context, profile string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flips = dropLast(m.flips, profile, func(e FlipEntry) Meta { return e.Meta })
+	return nil
+}
+
+func (m *MemoryStore) UndoEgg(ctx context.Context, profile string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.eggs = dropLast(m.eggs, profile, func(e EggEntry) Meta { return e.Meta })
+	return nil
+}
+
+func (m *MemoryStore) UndoMisty(ctx context.Context, profile string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.misty = dropLast(m.misty, profile, func(e MistyEntry) Meta { return e.Meta })
+	return nil
+}
+
+// dropLast removes the last entry logged for profile.
+func dropLast[T any](entries []T, profile string, meta func(T) Meta) []T {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if meta(entries[i]).Profile == profile {
+			return slices.Delete(entries, i, i+1)
+		}
+	}
+	return entries
+}
+
+func (m *MemoryStore) Reset(ctx context.Context, profile string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	filter := Filter{Profile: profile}
+	m.flips = slices.DeleteFunc(m.flips, func(e FlipEntry) bool { return filter.matches(e.Meta) })
+	m.eggs = slices.DeleteFunc(m.eggs, func(e EggEntry) bool { return filter.matches(e.Meta) })
+	m.misty = slices.DeleteFunc(m.misty, func(e MistyEntry) bool { return filter.matches(e.Meta) })
+	m.games = slices.DeleteFunc(m.games, func(g Game) bool { return filter.matches(g.Meta) })
+	return nil
+}
+
+func (m *MemoryStore) HeadsInfo(ctx context.Context, filter Filter) (totalFlips, headsCount int, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+	stats, err := m.Flips(ctx, filter)
+	return stats.TotalFlips, stats.TotalHeads, err
+}
+
+func (m *MemoryStore) TailsInfo(ctx context.Context, filter Filter) (totalFlips, tailsCount int, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+	stats, err := m.Flips(ctx, filter)
+	return stats.TotalFlips, stats.TotalTails, err
+}
+
+func (m *MemoryStore) Flips(ctx context.Context, filter Filter) (stats Stats, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, f := range m.flips {
+		if !filter.matches(f.Meta) {
+			continue
+		}
+		stats.TotalFlips += 2
+		stats.TotalHeads += f.Heads1 + f.Heads2
+		stats.TotalTails += 2 - f.Heads1 - f.Heads2
+		switch f.Heads1 + f.Heads2 {
+		case 2:
+			stats.DoubleHeads++
+		case 0:
+			stats.DoubleTails++
+		}
+	}
+	return
+}
+
+func (m *MemoryStore) GetEggStats(ctx context.Context, filter Filter) (stats EggStats, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.eggs {
+		if !filter.matches(e.Meta) {
+			continue
+		}
+		stats.TotalEntries++
+		if e.Heads == 1 {
+			stats.TotalHeads++
+		} else {
+			stats.TotalTails++
+		}
+		if !e.Mattered {
+			stats.TotalNotMattered++
+		} else if e.Heads == 1 {
+			stats.HeadsMattered++
+		}
+	}
+	return
+}
+
+func (m *MemoryStore) GetMistyStats(ctx context.Context, filter Filter) (stats MistyStats, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.misty {
+		if !filter.matches(e.Meta) {
+			continue
+		}
+		stats.TotalEntries++
+		stats.TotalHeads += e.Heads
+		stats.MaxHeads = max(stats.MaxHeads, e.Heads)
+	}
+	return
+}
+
+func (m *MemoryStore) GetMistyHistogram(ctx context.Context, filter Filter) ([]int, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var counts []int
+	for _, e := range m.misty {
+		if !filter.matches(e.Meta) {
+			continue
+		}
+		for len(counts) <= e.Heads {
+			counts = append(counts, 0)
+		}
+		counts[e.Heads]++
+	}
+	return counts, nil
+}
+
+func (m *MemoryStore) GetMatteredStats(ctx context.Context, table TableType, filter Filter) (stats MatteredStats, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	if !HasMattered(table) {
+		err = fmt.Errorf("table does not record whether flips mattered")
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.eggs {
+		if !filter.matches(e.Meta) {
+			continue
+		}
+		if e.Mattered {
+			stats.Mattered++
+			stats.HeadsMattered += e.Heads
+		} else {
+			stats.NotMattered++
+			stats.HeadsNotMattered += e.Heads
+		}
+	}
+	return
+}
+
+func (m *MemoryStore) FlipEntries(ctx context.Context, filter Filter, limit int) ([]FlipEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var entries []FlipEntry
+	for _, e := range m.flips {
+		if filter.matches(e.Meta) {
+			e.Meta = cloneMeta(e.Meta)
+			entries = append(entries, e)
+		}
+	}
+	return latest(entries, limit, func(e FlipEntry) string { return e.CreatedAt }), nil
+}
+
+func (m *MemoryStore) EggEntries(ctx context.Context, filter Filter, limit int) ([]EggEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var entries []EggEntry
+	for _, e := range m.eggs {
+		if filter.matches(e.Meta) {
+			e.Meta = cloneMeta(e.Meta)
+			entries = append(entries, e)
+		}
+	}
+	return latest(entries, limit, func(e EggEntry) string { return e.CreatedAt }), nil
+}
+
+func (m *MemoryStore) MistyEntries(ctx context.Context, filter Filter, limit int) ([]MistyEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var entries []MistyEntry
+	for _, e := range m.misty {
+		if filter.matches(e.Meta) {
+			e.Meta = cloneMeta(e.Meta)
+			entries = append(entries, e)
+		}
+	}
+	return latest(entries, limit, func(e MistyEntry) string { return e.CreatedAt }), nil
+}
+
+// latest sorts entries by when they happened and returns the last limit of
+// them, or all of them when limit <= 0.
+func latest[T any](entries []T, limit int, createdAt func(T) string) []T {
+	slices.SortStableFunc(entries, func(a, b T) int { return strings.Compare(createdAt(a), createdAt(b)) })
+	return last(entries, limit)
+}
+
+// last returns the last limit elements of s, or all of them when
+// limit <= 0.
+func last[T any](s []T, limit int) []T {
+	if limit <= 0 || limit >= len(s) {
+		return s
+	}
+	return s[len(s)-limit:]
+}
+
+func (m *MemoryStore) GetFlip(ctx context.Context, id int64) (FlipEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return FlipEntry{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i, err := findEntry(m.flips, id, func(e FlipEntry) int64 { return e.ID })
+	if err != nil {
+		return FlipEntry{}, err
+	}
+	e := m.flips[i]
+	e.Meta = cloneMeta(e.Meta)
+	return e, nil
+}
+
+func (m *MemoryStore) GetEgg(ctx context.Context, id int64) (EggEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return EggEntry{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i, err := findEntry(m.eggs, id, func(e EggEntry) int64 { return e.ID })
+	if err != nil {
+		return EggEntry{}, err
+	}
+	e := m.eggs[i]
+	e.Meta = cloneMeta(e.Meta)
+	return e, nil
+}
+
+func (m *MemoryStore) GetMisty(ctx context.Context, id int64) (MistyEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return MistyEntry{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i, err := findEntry(m.misty, id, func(e MistyEntry) int64 { return e.ID })
+	if err != nil {
+		return MistyEntry{}, err
+	}
+	e := m.misty[i]
+	e.Meta = cloneMeta(e.Meta)
+	return e, nil
+}
+
+func (m *MemoryStore) UpdateFlip(ctx context.Context, id int64, flipType FlipType) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	heads1, heads2, err := flipHeads(flipType)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i, err := findEntry(m.flips, id, func(e FlipEntry) int64 { return e.ID })
+	if err != nil {
+		return err
+	}
+	m.flips[i].Heads1, m.flips[i].Heads2 = heads1, heads2
+	return nil
+}
+
+func (m *MemoryStore) UpdateEgg(ctx context.Context, id int64, eggType EggType) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	heads, mattered, err := eggValues(eggType)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i, err := findEntry(m.eggs, id, func(e EggEntry) int64 { return e.ID })
+	if err != nil {
+		return err
+	}
+	m.eggs[i].Heads, m.eggs[i].Mattered = heads, mattered
+	return nil
+}
+
+func (m *MemoryStore) UpdateMisty(ctx context.Context, id int64, heads int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := validateMisty(heads); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i, err := findEntry(m.misty, id, func(e MistyEntry) int64 { return e.ID })
+	if err != nil {
+		return err
+	}
+	m.misty[i].Heads = heads
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, table TableType, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var err error
+	switch table {
+	case Kanga:
+		m.flips, err = deleteEntry(m.flips, id, func(e FlipEntry) int64 { return e.ID })
+	case Egg:
+		m.eggs, err = deleteEntry(m.eggs, id, func(e EggEntry) int64 { return e.ID })
+	case Misty:
+		m.misty, err = deleteEntry(m.misty, id, func(e MistyEntry) int64 { return e.ID })
+	default:
+		err = fmt.Errorf("unknown table: %d", table)
+	}
+	return err
+}
+
+func findEntry[T any](entries []T, id int64, entryID func(T) int64) (int, error) {
+	for i, e := range entries {
+		if entryID(e) == id {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %d", ErrNotFound, id)
+}
+
+func deleteEntry[T any](entries []T, id int64, entryID func(T) int64) ([]T, error) {
+	i, err := findEntry(entries, id, entryID)
+	if err != nil {
+		return entries, err
+	}
+	return append(entries[:i], entries[i+1:]...), nil
+}
+
+// Close is a no-op; the entries stay available.
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+func (m *MemoryStore) Profiles(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.profiles), nil
+}
+
+func (m *MemoryStore) AddProfile(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	name, err := NormalizeProfile(name)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var added bool
+	if m.profiles, added = insertName(m.profiles, name); !added {
+		return fmt.Errorf("%w: profile %q already exists", ErrInvalidValue, name)
+	}
+	return nil
+}
+
+func (m *MemoryStore) ActiveProfile(ctx context.Context) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.activeProfile, nil
+}
+
+func (m *MemoryStore) UseProfile(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	name, err := NormalizeProfile(name)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !slices.Contains(m.profiles, name) {
+		return fmt.Errorf("%w: no profile %q", ErrNotFound, name)
+	}
+	m.activeProfile = name
+	return nil
+}
+
+func (m *MemoryStore) Decks(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.decks), nil
+}
+
+func (m *MemoryStore) AddDeck(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	name, err := NormalizeDeck(name)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var added bool
+	if m.decks, added = insertName(m.decks, name); !added {
+		return fmt.Errorf("%w: deck %q already exists", ErrInvalidValue, name)
+	}
+	return nil
+}
+
+func (m *MemoryStore) ActiveDeck(ctx context.Context, profile string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.activeDecks[profile], nil
+}
+
+func (m *MemoryStore) UseDeck(ctx context.Context, profile, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if strings.ToLower(name) == NoDeck {
+		delete(m.activeDecks, profile)
+		return nil
+	}
+	name, err := NormalizeDeck(name)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(m.decks, name) {
+		return fmt.Errorf("%w: no deck %q", ErrNotFound, name)
+	}
+	m.activeDecks[profile] = name
+	return nil
+}
+
+// insertName adds name to the sorted names, reporting false if it is
+// already there.
+func insertName(names []string, name string) ([]string, bool) {
+	i, found := slices.BinarySearch(names, name)
+	if found {
+		return names, false
+	}
+	return slices.Insert(names, i, name), true
+}
+
+func (m *MemoryStore) EndGame(ctx context.Context, result GameResult, meta Meta, since time.Time) (Game, error) {
+	if err := ctx.Err(); err != nil {
+		return Game{}, err
+	}
+	switch result {
+	case Win, Loss, Draw:
+	default:
+		return Game{}, fmt.Errorf("%w: invalid game result %q", ErrInvalidValue, result)
+	}
+	meta, err := meta.validate()
+	if err != nil {
+		return Game{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	createdAt := meta.createdAt()
+	if since.IsZero() {
+		since = meta.At.Add(-firstGameWindow)
+		previous := ""
+		for _, g := range m.games {
+			if g.Profile == meta.Profile && g.CreatedAt <= createdAt && g.CreatedAt >= previous {
+				previous = g.CreatedAt
+			}
+		}
+		if previous != "" {
+			if since, err = ParseTime(previous); err != nil {
+				return Game{}, err
+			}
+		}
+	}
+
+	game := Game{ID: m.nextGameID, Result: result, CreatedAt: createdAt, Meta: meta}
+	m.nextGameID++
+	from := FormatTime(since)
+	link := func(e *Meta, at string) bool {
+		if e.Game != 0 || e.Profile != meta.Profile || at < from || at > createdAt {
+			return false
+		}
+		e.Game = game.ID
+		if e.Opponent == "" {
+			e.Opponent = meta.Opponent
+		}
+		return true
+	}
+	for i := range m.flips {
+		if link(&m.flips[i].Meta, m.flips[i].CreatedAt) {
+			game.Flips++
+		}
+	}
+	for i := range m.eggs {
+		if link(&m.eggs[i].Meta, m.eggs[i].CreatedAt) {
+			game.Eggs++
+		}
+	}
+	for i := range m.misty {
+		if link(&m.misty[i].Meta, m.misty[i].CreatedAt) {
+			game.Misty++
+		}
+	}
+	m.games = append(m.games, game)
+	game.Meta = cloneMeta(game.Meta)
+	return game, nil
+}
+
+func (m *MemoryStore) Games(ctx context.Context, filter Filter) ([]Game, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var games []Game
+	for _, g := range m.games {
+		if filter.matches(g.Meta) {
+			g.Meta = cloneMeta(g.Meta)
+			games = append(games, g)
+		}
+	}
+	return latest(games, 0, func(g Game) string { return g.CreatedAt }), nil
+}
+
+func (m *MemoryStore) GameLuck(ctx context.Context, filter Filter) ([]GameLuck, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var games []Game
+	for _, g := range m.games {
+		if filter.matches(g.Meta) {
+			games = append(games, g)
+		}
+	}
+	games = latest(games, 0, func(g Game) string { return g.CreatedAt })
+
+	lucks := make([]GameLuck, len(games))
+	index := make(map[int64]*GameLuck)
+	for i, g := range games {
+		lucks[i] = GameLuck{ID: g.ID, Result: g.Result}
+		index[g.ID] = &lucks[i]
+	}
+	for _, e := range m.flips {
+		if g, ok := index[e.Game]; ok {
+			g.Flips++
+			g.FlipHeads += e.Heads1 + e.Heads2
+		}
+	}
+	for _, e := range m.eggs {
+		if g, ok := index[e.Game]; ok {
+			g.Eggs++
+			g.EggHeads += e.Heads
+		}
+	}
+	for _, e := range m.misty {
+		if g, ok := index[e.Game]; ok {
+			g.Misty++
+			g.MistyHeads += e.Heads
+		}
+	}
+	return lucks, nil
+}
+
+func (m *MemoryStore) Matchups(ctx context.Context, filter Filter) ([]Matchup, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	byOpponent := make(map[string]*Matchup)
+	get := func(opponent string) *Matchup {
+		if _, ok := byOpponent[opponent]; !ok {
+			byOpponent[opponent] = &Matchup{Opponent: opponent}
+		}
+		return byOpponent[opponent]
+	}
+	for _, e := range m.flips {
+		if filter.matches(e.Meta) {
+			mu := get(e.Opponent)
+			mu.Flips++
+			mu.FlipHeads += e.Heads1 + e.Heads2
+		}
+	}
+	for _, e := range m.eggs {
+		if filter.matches(e.Meta) {
+			mu := get(e.Opponent)
+			mu.Eggs++
+			mu.EggHeads += e.Heads
+		}
+	}
+	for _, e := range m.misty {
+		if filter.matches(e.Meta) {
+			mu := get(e.Opponent)
+			mu.Misty++
+			mu.MistyHeads += e.Heads
+		}
+	}
+	for _, g := range m.games {
+		if filter.matches(g.Meta) {
+			mu := get(g.Opponent)
+			switch g.Result {
+			case Win:
+				mu.Wins++
+			case Loss:
+				mu.Losses++
+			case Draw:
+				mu.Draws++
+			}
+		}
+	}
+
+	opponents := slices.Sorted(maps.Keys(byOpponent))
+	matchups := make([]Matchup, len(opponents))
+	for i, opponent := range opponents {
+		matchups[i] = *byOpponent[opponent]
+	}
+	return matchups, nil
+}
@@ -0,0 +1,52 @@
+package data
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryStoreIDs(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStore()
+	err := m.InsertBatch(ctx, Batch{Flips: []FlipType{HH, TT}, Eggs: []EggType{HX}, Misty: []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	flips, err := m.FlipEntries(ctx, Filter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eggs, err := m.EggEntries(ctx, Filter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	misty, err := m.MistyEntries(ctx, Filter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every table numbers its entries on its own, as in SQLite
+	if flips[0].ID != 1 || flips[1].ID != 2 || eggs[0].ID != 1 || misty[0].ID != 1 {
+		t.Errorf("ids = %d, %d, %d, %d, want 1, 2, 1, 1", flips[0].ID, flips[1].ID, eggs[0].ID, misty[0].ID)
+	}
+}
+
+func TestMemoryStoreTagsCopied(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStore()
+	if err := m.InsertFlip(ctx, HH, Meta{Tags: []string{"x"}}); err != nil {
+		t.Fatal(err)
+	}
+	e, err := m.GetFlip(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Tags[0] = "changed"
+	entries, err := m.FlipEntries(ctx, Filter{}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries[0].Tags[0] = "changed"
+	if e, _ := m.GetFlip(ctx, 1); e.Tags[0] != "x" {
+		t.Errorf("stored tags = %v after changing returned ones, want [x]", e.Tags)
+	}
+}
@@ -0,0 +1,205 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Meta is the optional context logged with an entry.
+type Meta struct {
+	Note string
+	Tags []string
+	// At is when the entry happened. The zero time means now.
+	At time.Time
+	// Profile is the player the entry is logged for. The empty profile
+	// means DefaultProfile.
+	Profile string
+	// Deck is the deck the entry was played with, or "" for none.
+	Deck string
+	// Opponent is the archetype the entry was played against, or "" when
+	// it isn't known.
+	Opponent string
+	// Game is the id of the game the entry is linked to, or 0. Entries are
+	// linked when their game ends, so it is ignored when logging.
+	Game int64
+}
+
+// Filter restricts the entries statistics are computed from. The zero
+// Filter matches every entry.
+type Filter struct {
+	// Tags lists tags an entry must all carry.
+	Tags []string
+	// From and To restrict entries to those logged at or after From and
+	// before To. The zero time leaves that side open.
+	From, To time.Time
+	// Profile restricts entries to a single player. The empty profile
+	// matches every player.
+	Profile string
+	// Deck restricts entries to a single deck, or to entries without a deck
+	// when it is NoDeck. The empty deck matches every deck.
+	Deck string
+	// Opponent restricts entries to those played against an archetype.
+	// The empty opponent matches every entry.
+	Opponent string
+}
+
+// NormalizeTags lowercases tags and drops duplicates. Tags can't be empty
+// or contain commas or spaces.
+func NormalizeTags(tags []string) ([]string, error) {
+	var normalized []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || strings.ContainsAny(tag, ", \t\n") {
+			return nil, fmt.Errorf("%w: invalid tag %q", ErrInvalidValue, tag)
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized, nil
+}
+
+// encodeTags stores tags as ",a,b," so a single tag can be matched with
+// instr(tags, ',a,').
+func encodeTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return "," + strings.Join(tags, ",") + ","
+}
+
+func decodeTags(s string) []string {
+	s = strings.Trim(s, ",")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// validate checks and normalizes the meta before it is stored.
+func (m Meta) validate() (Meta, error) {
+	tags, err := NormalizeTags(m.Tags)
+	if err != nil {
+		return Meta{}, err
+	}
+	m.Tags = tags
+	m.Game = 0
+	if m.Profile == "" {
+		m.Profile = DefaultProfile
+	}
+	if m.Profile, err = NormalizeProfile(m.Profile); err != nil {
+		return Meta{}, err
+	}
+	if m.Deck != "" {
+		if m.Deck, err = NormalizeDeck(m.Deck); err != nil {
+			return Meta{}, err
+		}
+	}
+	if m.Opponent != "" {
+		if m.Opponent, err = NormalizeOpponent(m.Opponent); err != nil {
+			return Meta{}, err
+		}
+	}
+
+	now := time.Now()
+	if m.At.IsZero() {
+		m.At = now
+	}
+	if err := validateTime(m.At); err != nil {
+		return Meta{}, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+	}
+	m.At = m.At.UTC().Truncate(time.Second)
+	return m, nil
+}
+
+// createdAt returns the created_at value of an entry logged with m.
+func (m Meta) createdAt() string {
+	return FormatTime(m.At)
+}
+
+// where returns an SQL condition matching the filter, along with its
+// arguments.
+func (f Filter) where() (string, []any) {
+	conds := []string{"1"}
+	var args []any
+	for _, tag := range f.Tags {
+		conds = append(conds, "instr(tags, ?) > 0")
+		args = append(args, ","+strings.ToLower(tag)+",")
+	}
+	if f.Profile != "" {
+		conds = append(conds, "profile = ?")
+		args = append(args, strings.ToLower(f.Profile))
+	}
+	if f.Deck != "" {
+		conds = append(conds, "deck = ?")
+		args = append(args, f.deck())
+	}
+	if f.Opponent != "" {
+		conds = append(conds, "opponent = ?")
+		args = append(args, strings.ToLower(f.Opponent))
+	}
+	if !f.From.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, FormatTime(f.From))
+	}
+	if !f.To.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, FormatTime(f.To))
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// counted reports whether stats over the filter can be read from the stats
+// counters, which are kept per profile.
+func (f Filter) counted() bool {
+	return len(f.Tags) == 0 && f.From.IsZero() && f.To.IsZero() && f.Deck == "" && f.Opponent == ""
+}
+
+// deck returns the deck column value the filter matches.
+func (f Filter) deck() string {
+	deck := strings.ToLower(f.Deck)
+	if deck == NoDeck {
+		return ""
+	}
+	return deck
+}
+
+// matches reports whether an entry logged with m passes the filter.
+func (f Filter) matches(m Meta) bool {
+	for _, tag := range f.Tags {
+		if !slices.Contains(m.Tags, strings.ToLower(tag)) {
+			return false
+		}
+	}
+	if f.Profile != "" && m.Profile != strings.ToLower(f.Profile) {
+		return false
+	}
+	if f.Deck != "" && m.Deck != f.deck() {
+		return false
+	}
+	if f.Opponent != "" && m.Opponent != strings.ToLower(f.Opponent) {
+		return false
+	}
+	if !f.From.IsZero() && m.At.Before(f.From.Truncate(time.Second)) {
+		return false
+	}
+	if !f.To.IsZero() && !m.At.Before(f.To.Truncate(time.Second)) {
+		return false
+	}
+	return true
+}
+
+// addColumn adds a column to a table created by an older version of kanga.
+func addColumn(ctx context.Context, db *sql.DB, table, column, definition string) error {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+	_, err = db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
@@ -1,42 +1,113 @@
 package data
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
 )
 
 type MistyStats struct {
 	TotalEntries int
 	TotalHeads   int
+	MaxHeads     int
 }
 
-func InsertMisty(db *sql.DB, heads int) {
+// validateMisty checks the number of heads of a misty attempt.
+func validateMisty(heads int) error {
+	if heads < 0 {
+		return fmt.Errorf("%w: misty heads must not be negative, got %d", ErrInvalidValue, heads)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) InsertMisty(ctx context.Context, heads int, meta Meta) error {
+	if err := validateMisty(heads); err != nil {
+		return err
+	}
+	meta, err := meta.validate()
+	if err != nil {
+		return err
+	}
+
 	stmt := `
-	INSERT INTO misty (heads, created_at)
-	VALUES (?, CURRENT_TIMESTAMP)`
+	INSERT INTO misty (heads, created_at, note, tags, profile, deck, opponent)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := db.Exec(stmt, heads)
+	_, err = s.db.ExecContext(ctx, stmt, heads, meta.createdAt(), meta.Note, encodeTags(meta.Tags), meta.Profile, meta.Deck, meta.Opponent)
 	if err != nil {
-		fmt.Printf("Failed to insert misty entry: %v\n", err)
+		return fmt.Errorf("failed to insert misty entry: %w", err)
 	}
+	return nil
 }
 
-func GetMistyStats(db *sql.DB) (MistyStats, error) {
-	var stats MistyStats
-	err := db.QueryRow("SELECT COUNT(*), IFNULL(SUM(heads), 0) FROM misty").Scan(&stats.TotalEntries, &stats.TotalHeads)
+func (s *SQLiteStore) GetMistyStats(ctx context.Context, filter Filter) (MistyStats, error) {
+	c, err := s.counters(ctx, "misty", filter)
 	if err != nil {
-		return MistyStats{}, fmt.Errorf("failed to get misty stats: %v", err)
+		return MistyStats{}, fmt.Errorf("failed to get misty stats: %w", err)
+	}
+	stats := MistyStats{TotalEntries: c["rows"], TotalHeads: c["heads"]}
+
+	// MAX uses idx_misty_heads, so the record chain doesn't need a counter
+	where, args := filter.where()
+	err = s.db.QueryRowContext(ctx, "SELECT IFNULL(MAX(heads), 0) FROM misty WHERE "+where, args...).Scan(&stats.MaxHeads)
+	if err != nil {
+		return MistyStats{}, fmt.Errorf("failed to get misty stats: %w", err)
 	}
 	return stats, nil
 }
 
-func UndoMisty(db *sql.DB) {
+// GetMistyHistogram returns the number of attempts for each heads count,
+// indexed by heads.
+func (s *SQLiteStore) GetMistyHistogram(ctx context.Context, filter Filter) ([]int, error) {
+	where, args := filter.where()
+	rows, err := s.db.QueryContext(ctx, "SELECT heads, COUNT(*) FROM misty WHERE heads >= 0 AND "+where+" GROUP BY heads ORDER BY heads", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get misty histogram: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []int
+	for rows.Next() {
+		var heads, count int
+		if err := rows.Scan(&heads, &count); err != nil {
+			return nil, err
+		}
+		for len(counts) <= heads {
+			counts = append(counts, 0)
+		}
+		counts[heads] = count
+	}
+	return counts, rows.Err()
+}
+
+func (s *SQLiteStore) UndoMisty(ctx context.Context, profile string) error {
 	stmt := `
 	DELETE FROM misty
-	WHERE id = (SELECT MAX(id) FROM misty)
+	WHERE id = (SELECT MAX(id) FROM misty WHERE profile = ?)
 	`
-	_, err := db.Exec(stmt)
+	_, err := s.db.ExecContext(ctx, stmt, profile)
 	if err != nil {
-		fmt.Printf("Failed to undo misty entry: %v\n", err)
+		return fmt.Errorf("failed to undo misty entry: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) MistyEntries(ctx context.Context, filter Filter, limit int) ([]MistyEntry, error) {
+	where, args := filter.where()
+	rows, err := s.db.QueryContext(ctx, lastRows("SELECT id, heads, created_at, note, tags, profile, deck, opponent, IFNULL(game_id, 0) FROM misty WHERE "+where), append(args, sqlLimit(limit))...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []MistyEntry
+	for rows.Next() {
+		var e MistyEntry
+		var tags string
+		if err := rows.Scan(&e.ID, &e.Heads, &e.CreatedAt, &e.Note, &tags, &e.Profile, &e.Deck, &e.Opponent, &e.Game); err != nil {
+			return nil, err
+		}
+		e.Tags = decodeTags(tags)
+		entries = append(entries, e)
 	}
+	return entries, rows.Err()
 }
@@ -0,0 +1,161 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultProfile is the profile entries are logged for until another one is
+// put in use. Entries logged before profiles existed belong to it.
+const DefaultProfile = "default"
+
+// AllProfiles is the name selecting the entries of every profile. It can't
+// be used as a profile name.
+const AllProfiles = "all"
+
+// activeProfileKey is the config key holding the profile in use.
+const activeProfileKey = "active_profile"
+
+// NormalizeProfile lowercases a profile name and checks it. Names follow the
+// rules of tags and can't be AllProfiles.
+func NormalizeProfile(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || strings.ContainsAny(name, ", \t\n") {
+		return "", fmt.Errorf("%w: invalid profile name %q", ErrInvalidValue, name)
+	}
+	if name == AllProfiles {
+		return "", fmt.Errorf("%w: profile name %q is reserved", ErrInvalidValue, name)
+	}
+	return name, nil
+}
+
+// createProfiles creates the profiles and config tables, and the profile
+// column of the tables created by older versions.
+func createProfiles(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS profiles (
+		name TEXT PRIMARY KEY,
+		created_at DATETIME DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+	);`)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS config (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);`)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, "INSERT OR IGNORE INTO profiles (name) VALUES (?)", DefaultProfile); err != nil {
+		return err
+	}
+
+	for _, table := range checkedTables {
+		if err := addColumn(ctx, db, table, "profile", fmt.Sprintf("TEXT NOT NULL DEFAULT '%s'", DefaultProfile)); err != nil {
+			return err
+		}
+		_, err = db.ExecContext(ctx, fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%[1]s_profile ON %[1]s (profile, created_at);", table))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Profiles returns the name of every profile, in alphabetical order.
+func (s *SQLiteStore) Profiles(ctx context.Context) ([]string, error) {
+	var names []string
+	err := queryRows(ctx, s.db, "SELECT name FROM profiles ORDER BY name", nil, func(rows *sql.Rows) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		names = append(names, name)
+		return nil
+	})
+	return names, err
+}
+
+// AddProfile creates a profile.
+func (s *SQLiteStore) AddProfile(ctx context.Context, name string) error {
+	name, err := NormalizeProfile(name)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, "INSERT OR IGNORE INTO profiles (name) VALUES (?)", name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: profile %q already exists", ErrInvalidValue, name)
+	}
+	return nil
+}
+
+// ActiveProfile returns the profile in use.
+func (s *SQLiteStore) ActiveProfile(ctx context.Context) (string, error) {
+	var name string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM config WHERE key = ?", activeProfileKey).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultProfile, nil
+	}
+	return name, err
+}
+
+// UseProfile puts a profile in use. It returns ErrNotFound if the profile
+// doesn't exist.
+func (s *SQLiteStore) UseProfile(ctx context.Context, name string) error {
+	name, err := NormalizeProfile(name)
+	if err != nil {
+		return err
+	}
+	if err := s.checkProfile(ctx, name); err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "INSERT OR REPLACE INTO config (key, value) VALUES (?, ?)", activeProfileKey, name)
+	return err
+}
+
+// checkProfile returns ErrNotFound if the profile doesn't exist.
+func (s *SQLiteStore) checkProfile(ctx context.Context, name string) error {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM profiles WHERE name = ?", name).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: no profile %q", ErrNotFound, name)
+	}
+	return nil
+}
+
+// checkProfiles finds rows logged for a profile that doesn't exist.
+func checkProfiles(ctx context.Context, q querier) ([]Problem, error) {
+	var problems []Problem
+	for _, table := range checkedTables {
+		query := fmt.Sprintf("SELECT DISTINCT profile FROM %s WHERE profile NOT IN (SELECT name FROM profiles) ORDER BY profile", table)
+		err := queryRows(ctx, q, query, nil, func(rows *sql.Rows) error {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			problems = append(problems, Problem{
+				Table:   table,
+				Message: fmt.Sprintf("rows logged for unknown profile %q", name),
+				Fix:     "create the profile",
+				repair: func(ctx context.Context, q querier) error {
+					_, err := q.ExecContext(ctx, "INSERT OR IGNORE INTO profiles (name) VALUES (?)", name)
+					return err
+				},
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return problems, nil
+}
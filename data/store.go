@@ -0,0 +1,210 @@
+package data
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Store is the storage behind kanga. SQLiteStore keeps the data in a
+// database file, MemoryStore keeps it in memory, which is handy for tests
+// and for programs embedding kanga.
+type Store interface {
+	InsertFlip(ctx context.Context, flipType FlipType, meta Meta) error
+	InsertExeggutor(ctx context.Context, eggType EggType, meta Meta) error
+	InsertMisty(ctx context.Context, heads int, meta Meta) error
+	// InsertBatch validates every entry of the batches, then inserts them
+	// all at once, in order. Nothing is written if any entry is invalid.
+	InsertBatch(ctx context.Context, batches ...Batch) error
+
+	// Undo, UndoEgg and UndoMisty delete the last entry of a card logged
+	// for profile.
+	Undo(ctx context.Context, profile string) error
+	UndoEgg(ctx context.Context, profile string) error
+	UndoMisty(ctx context.Context, profile string) error
+	// Reset deletes the entries and games of profile, or of every profile
+	// when it is empty.
+	Reset(ctx context.Context, profile string) error
+
+	HeadsInfo(ctx context.Context, filter Filter) (totalFlips, headsCount int, err error)
+	TailsInfo(ctx context.Context, filter Filter) (totalFlips, tailsCount int, err error)
+	Flips(ctx context.Context, filter Filter) (Stats, error)
+	GetEggStats(ctx context.Context, filter Filter) (EggStats, error)
+	GetMistyStats(ctx context.Context, filter Filter) (MistyStats, error)
+	GetMistyHistogram(ctx context.Context, filter Filter) ([]int, error)
+	GetMatteredStats(ctx context.Context, table TableType, filter Filter) (MatteredStats, error)
+
+	// FlipEntries, EggEntries and MistyEntries return the last limit
+	// entries matching the filter, or all of them when limit <= 0, in the
+	// order they happened.
+	FlipEntries(ctx context.Context, filter Filter, limit int) ([]FlipEntry, error)
+	EggEntries(ctx context.Context, filter Filter, limit int) ([]EggEntry, error)
+	MistyEntries(ctx context.Context, filter Filter, limit int) ([]MistyEntry, error)
+
+	// GetFlip, GetEgg and GetMisty return a single entry, or ErrNotFound.
+	GetFlip(ctx context.Context, id int64) (FlipEntry, error)
+	GetEgg(ctx context.Context, id int64) (EggEntry, error)
+	GetMisty(ctx context.Context, id int64) (MistyEntry, error)
+
+	// UpdateFlip, UpdateEgg and UpdateMisty change the result of an entry,
+	// keeping its timestamp and meta.
+	UpdateFlip(ctx context.Context, id int64, flipType FlipType) error
+	UpdateEgg(ctx context.Context, id int64, eggType EggType) error
+	UpdateMisty(ctx context.Context, id int64, heads int) error
+	// Delete removes an entry from a table.
+	Delete(ctx context.Context, table TableType, id int64) error
+
+	// Profiles returns the name of every profile, in alphabetical order.
+	Profiles(ctx context.Context) ([]string, error)
+	AddProfile(ctx context.Context, name string) error
+	ActiveProfile(ctx context.Context) (string, error)
+	// UseProfile puts a profile in use, or returns ErrNotFound.
+	UseProfile(ctx context.Context, name string) error
+
+	// Decks returns the name of every deck, in alphabetical order.
+	Decks(ctx context.Context) ([]string, error)
+	AddDeck(ctx context.Context, name string) error
+	// ActiveDeck returns the deck a profile uses, or "" when it uses none.
+	ActiveDeck(ctx context.Context, profile string) (string, error)
+	// UseDeck puts a deck in use for a profile, or stops using one when
+	// name is NoDeck. It returns ErrNotFound if the deck doesn't exist.
+	UseDeck(ctx context.Context, profile, name string) error
+
+	// EndGame closes the current game of meta's profile, linking it the
+	// entries logged since the previous game.
+	EndGame(ctx context.Context, result GameResult, meta Meta, since time.Time) (Game, error)
+	// Games returns every game matching filter, in the order they ended.
+	Games(ctx context.Context, filter Filter) ([]Game, error)
+	// GameLuck returns the coins flipped in every game matching filter.
+	GameLuck(ctx context.Context, filter Filter) ([]GameLuck, error)
+	// Matchups returns the attacks and games matching filter grouped by
+	// opponent.
+	Matchups(ctx context.Context, filter Filter) ([]Matchup, error)
+
+	Close() error
+}
+
+// Importer is a Store that can import the CSV files written by DumpCsv.
+type Importer interface {
+	Store
+	ReadCsv(ctx context.Context, folder string, tables map[TableType]bool, skipInvalid bool, profile string) (ImportResult, error)
+}
+
+// Maintainer is a Store kept in a file that can be checked and repaired.
+type Maintainer interface {
+	Store
+	// Check scans the stored data for invalid, duplicated or misplaced
+	// rows.
+	Check(ctx context.Context) ([]Problem, error)
+	// Repair backs the data up, then fixes every problem it can. It returns
+	// the problems found and the path of the backup.
+	Repair(ctx context.Context) ([]Problem, string, error)
+	// RebuildCounters recomputes the stats counters from the tables.
+	RebuildCounters(ctx context.Context) error
+}
+
+// Batch holds entries logged together, in the order they were given.
+type Batch struct {
+	Flips []FlipType
+	Eggs  []EggType
+	Misty []int
+	// Meta is logged with every entry of the batch.
+	Meta Meta
+}
+
+// Len returns the number of entries in the batch.
+func (b Batch) Len() int {
+	return len(b.Flips) + len(b.Eggs) + len(b.Misty)
+}
+
+// validate checks every entry of the batch and returns it with its meta
+// normalized.
+func (b Batch) validate() (Batch, error) {
+	meta, err := b.Meta.validate()
+	if err != nil {
+		return Batch{}, err
+	}
+	b.Meta = meta
+	for _, flipType := range b.Flips {
+		if _, _, err := flipHeads(flipType); err != nil {
+			return Batch{}, err
+		}
+	}
+	for _, eggType := range b.Eggs {
+		if _, _, err := eggValues(eggType); err != nil {
+			return Batch{}, err
+		}
+	}
+	for _, heads := range b.Misty {
+		if err := validateMisty(heads); err != nil {
+			return Batch{}, err
+		}
+	}
+	return b, nil
+}
+
+// validateBatches validates every batch and returns them normalized.
+func validateBatches(batches []Batch) ([]Batch, error) {
+	validated := make([]Batch, len(batches))
+	for i, batch := range batches {
+		batch, err := batch.validate()
+		if err != nil {
+			return nil, err
+		}
+		validated[i] = batch
+	}
+	return validated, nil
+}
+
+// FlipEntry is a logged Kangaskhan attack.
+type FlipEntry struct {
+	ID        int64
+	Heads1    int
+	Heads2    int
+	CreatedAt string
+	Meta
+}
+
+// EggEntry is a logged Exeggutor attack.
+type EggEntry struct {
+	ID        int64
+	Heads     int
+	Mattered  bool
+	CreatedAt string
+	Meta
+}
+
+// MistyEntry is a logged Misty attempt.
+type MistyEntry struct {
+	ID        int64
+	Heads     int
+	CreatedAt string
+	Meta
+}
+
+// Result returns the attack the way it is typed on the command line, e.g.
+// "HT".
+func (e FlipEntry) Result() string {
+	return coin(e.Heads1) + coin(e.Heads2)
+}
+
+// Result returns the attack the way it is typed on the command line, e.g.
+// "HX".
+func (e EggEntry) Result() string {
+	if !e.Mattered {
+		return coin(e.Heads) + "X"
+	}
+	return coin(e.Heads)
+}
+
+// Result returns the number of heads of the attempt.
+func (e MistyEntry) Result() string {
+	return strconv.Itoa(e.Heads)
+}
+
+func coin(heads int) string {
+	if heads == 1 {
+		return "H"
+	}
+	return "T"
+}
@@ -0,0 +1,121 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TimeLayout is the layout timestamps are shown in. They are stored as UTC
+// RFC 3339, see FormatTime.
+const TimeLayout = "2006-01-02 15:04:05"
+
+// legacyLayouts are the zone-less layouts older versions and hand-edited CSV
+// files wrote timestamps in. They are read as UTC.
+var legacyLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// FormatTime formats t the way created_at is stored: RFC 3339 in UTC, to
+// the second, so stored timestamps sort chronologically as text.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ParseTime parses a created_at timestamp: RFC 3339, or one of the layouts
+// older versions wrote, which are UTC.
+func ParseTime(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err == nil {
+		return t, nil
+	}
+	for _, layout := range legacyLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: invalid timestamp %q", ErrInvalidValue, s)
+}
+
+// NormalizeTime rewrites a timestamp in any layout ParseTime accepts the way
+// created_at is stored.
+func NormalizeTime(s string) (string, error) {
+	t, err := ParseTime(s)
+	if err != nil {
+		return "", err
+	}
+	return FormatTime(t), nil
+}
+
+// maxClockSkew is how far in the future a timestamp may be, to allow for
+// clocks that are slightly off.
+const maxClockSkew = time.Minute
+
+// earliestTime is the release of the Pokémon Trading Card Game. Nothing can
+// have been logged before it.
+var earliestTime = time.Date(1996, time.October, 20, 0, 0, 0, 0, time.UTC)
+
+// validateTime rejects timestamps from before the card game existed or from
+// the future.
+func validateTime(t time.Time) error {
+	if t.Before(earliestTime) {
+		return fmt.Errorf("timestamp %s is before the card game existed", FormatTime(t))
+	}
+	if t.After(time.Now().Add(maxClockSkew)) {
+		return fmt.Errorf("timestamp %s is in the future", FormatTime(t))
+	}
+	return nil
+}
+
+// storedTimeGlob matches timestamps already stored the way FormatTime
+// writes them.
+const storedTimeGlob = "[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9]Z"
+
+// normalizeTimestamps rewrites the created_at of rows written by older
+// versions, which used SQLite's CURRENT_TIMESTAMP or copied CSV values
+// as-is. It runs once as a migration; values that can't be parsed are left
+// for the database check to report.
+func normalizeTimestamps(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"flips", "exeggutor", "misty"} {
+		// CAST keeps the driver from converting the value before we see it
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf(
+			"SELECT id, CAST(created_at AS TEXT) FROM %s WHERE created_at IS NOT NULL AND created_at NOT GLOB ?", table), storedTimeGlob)
+		if err != nil {
+			return err
+		}
+		updates := make(map[int64]string)
+		for rows.Next() {
+			var id int64
+			var createdAt string
+			if err := rows.Scan(&id, &createdAt); err != nil {
+				rows.Close()
+				return err
+			}
+			if normalized, err := NormalizeTime(createdAt); err == nil {
+				updates[id] = normalized
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for id, createdAt := range updates {
+			_, err := tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET created_at = ? WHERE id = ?", table), createdAt, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,56 @@
+package sim
+
+import (
+	"math/rand/v2"
+
+	"github.com/alexstory/kanga/data"
+)
+
+// Result holds the outcome of simulating many attacks of a single card.
+type Result struct {
+	Card    data.TableType
+	Attacks int
+	// Heads[k] is the number of attacks that produced exactly k heads.
+	Heads []int
+}
+
+// Flip returns 1 for heads with probability p, 0 otherwise.
+func Flip(r *rand.Rand, p float64) int {
+	if r.Float64() < p {
+		return 1
+	}
+	return 0
+}
+
+// Attack performs the coin flips of a single attack and returns the number
+// of heads: two coins for Kangaskhan, one for Exeggutor and flip-until-tails
+// for Misty. p must be below 1, or a Misty attempt never ends.
+func Attack(r *rand.Rand, card data.TableType, p float64) int {
+	switch card {
+	case data.Kanga:
+		return Flip(r, p) + Flip(r, p)
+	case data.Egg:
+		return Flip(r, p)
+	case data.Misty:
+		heads := 0
+		for Flip(r, p) == 1 {
+			heads++
+		}
+		return heads
+	}
+	return 0
+}
+
+// Run simulates n attacks of the card with a fair coin.
+func Run(card data.TableType, n int, seed uint64) Result {
+	r := rand.New(rand.NewPCG(seed, seed))
+	res := Result{Card: card, Attacks: n}
+	for i := 0; i < n; i++ {
+		heads := Attack(r, card, 0.5)
+		for len(res.Heads) <= heads {
+			res.Heads = append(res.Heads, 0)
+		}
+		res.Heads[heads]++
+	}
+	return res
+}
@@ -0,0 +1,387 @@
+package stats
+
+import "math"
+
+// NormalCDF returns P(Z <= z) for a standard normal variable.
+func NormalCDF(z float64) float64 {
+	return 0.5 * math.Erfc(-z/math.Sqrt2)
+}
+
+// MeanVariance returns the mean and population variance of a histogram
+// where counts[k] is the number of observations with value k.
+func MeanVariance(counts []int) (mean, variance float64) {
+	total := 0
+	for k, c := range counts {
+		total += c
+		mean += float64(k * c)
+	}
+	if total == 0 {
+		return 0, 0
+	}
+	mean /= float64(total)
+	for k, c := range counts {
+		d := float64(k) - mean
+		variance += d * d * float64(c)
+	}
+	variance /= float64(total)
+	return
+}
+
+// Total returns the sum of all counts in a histogram.
+func Total(counts []int) int {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	return total
+}
+
+// MeanPercentile returns the percentile (0-100) of an observed sample mean
+// over n observations against a population with the given mean and variance,
+// using the normal approximation of the sampling distribution.
+func MeanPercentile(observed, mean, variance float64, n int) float64 {
+	if n == 0 {
+		return 50
+	}
+	if variance == 0 {
+		switch {
+		case observed > mean:
+			return 100
+		case observed < mean:
+			return 0
+		}
+		return 50
+	}
+	z := (observed - mean) / math.Sqrt(variance/float64(n))
+	return NormalCDF(z) * 100
+}
+
+// ChiSquareSF returns P(X >= x) for a chi-square variable with df degrees of
+// freedom.
+func ChiSquareSF(x float64, df int) float64 {
+	if df <= 0 {
+		return math.NaN()
+	}
+	if x <= 0 {
+		return 1
+	}
+	return gammaQ(float64(df)/2, x/2)
+}
+
+// ChiSquareFit runs Pearson's goodness-of-fit test of observed counts against
+// expected counts and returns the statistic, degrees of freedom and p-value.
+// Buckets with no expected count are ignored.
+func ChiSquareFit(observed []int, expected []float64) (chi2 float64, df int, p float64) {
+	for i, e := range expected {
+		if e <= 0 {
+			continue
+		}
+		d := float64(observed[i]) - e
+		chi2 += d * d / e
+		df++
+	}
+	df--
+	if df <= 0 {
+		return chi2, 0, math.NaN()
+	}
+	return chi2, df, ChiSquareSF(chi2, df)
+}
+
+// gammaQ returns the regularized upper incomplete gamma function Q(a, x).
+func gammaQ(a, x float64) float64 {
+	if x < a+1 {
+		return 1 - gammaPSeries(a, x)
+	}
+	return gammaQFraction(a, x)
+}
+
+func gammaPSeries(a, x float64) float64 {
+	lg, _ := math.Lgamma(a)
+	sum := 1 / a
+	term := sum
+	for n := 1; n < 1000; n++ {
+		term *= x / (a + float64(n))
+		sum += term
+		if math.Abs(term) < math.Abs(sum)*1e-15 {
+			break
+		}
+	}
+	return sum * math.Exp(-x+a*math.Log(x)-lg)
+}
+
+func gammaQFraction(a, x float64) float64 {
+	const tiny = 1e-300
+	lg, _ := math.Lgamma(a)
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lg) * h
+}
+
+// zCritical95 is the two-sided 95% critical value of the standard normal.
+const zCritical95 = 1.959963984540054
+
+// TwoProportionZ tests whether the success rates x1/n1 and x2/n2 differ,
+// using the pooled two-proportion z-test. It returns the z statistic and the
+// two-sided p-value.
+func TwoProportionZ(x1, n1, x2, n2 int) (z, p float64) {
+	if n1 == 0 || n2 == 0 {
+		return 0, math.NaN()
+	}
+	p1 := float64(x1) / float64(n1)
+	p2 := float64(x2) / float64(n2)
+	pooled := float64(x1+x2) / float64(n1+n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(n1) + 1/float64(n2)))
+	if se == 0 {
+		return 0, 1
+	}
+	z = (p1 - p2) / se
+	return z, 2 * NormalCDF(-math.Abs(z))
+}
+
+// DiffCI returns the 95% Wald confidence interval of x1/n1 - x2/n2.
+func DiffCI(x1, n1, x2, n2 int) (lo, hi float64) {
+	if n1 == 0 || n2 == 0 {
+		return math.NaN(), math.NaN()
+	}
+	p1 := float64(x1) / float64(n1)
+	p2 := float64(x2) / float64(n2)
+	se := math.Sqrt(p1*(1-p1)/float64(n1) + p2*(1-p2)/float64(n2))
+	d := p1 - p2
+	return d - zCritical95*se, d + zCritical95*se
+}
+
+// WilsonCI returns the 95% Wilson score interval of the success rate x/n,
+// which unlike the Wald interval stays sensible for small samples.
+func WilsonCI(x, n int) (lo, hi float64) {
+	if n == 0 {
+		return math.NaN(), math.NaN()
+	}
+	p := float64(x) / float64(n)
+	z2 := zCritical95 * zCritical95
+	den := 1 + z2/float64(n)
+	center := (p + z2/(2*float64(n))) / den
+	half := zCritical95 * math.Sqrt(p*(1-p)/float64(n)+z2/(4*float64(n)*float64(n))) / den
+	return center - half, center + half
+}
+
+// ChiSquareHomogeneity tests whether the success rates successes[i]/totals[i]
+// are the same in every group, using Pearson's chi-square test on the k x 2
+// table. Groups with no trials are ignored. It returns the statistic, degrees
+// of freedom, p-value and the smallest expected cell count.
+func ChiSquareHomogeneity(successes, totals []int) (chi2 float64, df int, p, minExpected float64) {
+	x, n := 0, 0
+	for i := range totals {
+		x += successes[i]
+		n += totals[i]
+	}
+	if n == 0 || x == 0 || x == n {
+		return 0, 0, math.NaN(), 0
+	}
+	rate := float64(x) / float64(n)
+	minExpected = math.Inf(1)
+	groups := 0
+	for i, t := range totals {
+		if t == 0 {
+			continue
+		}
+		groups++
+		cells := [2][2]float64{
+			{float64(successes[i]), float64(t) * rate},
+			{float64(t - successes[i]), float64(t) * (1 - rate)},
+		}
+		for _, c := range cells {
+			d := c[0] - c[1]
+			chi2 += d * d / c[1]
+			minExpected = math.Min(minExpected, c[1])
+		}
+	}
+	df = groups - 1
+	if df <= 0 {
+		return chi2, 0, math.NaN(), minExpected
+	}
+	return chi2, df, ChiSquareSF(chi2, df), minExpected
+}
+
+// FisherExact returns the two-sided p-value of Fisher's exact test for the
+// 2x2 table [[a, b], [c, d]].
+func FisherExact(a, b, c, d int) float64 {
+	row1, col1, n := a+b, a+c, a+b+c+d
+	if n == 0 {
+		return 1
+	}
+	observed := hypergeomLogP(a, row1, col1, n)
+	lo := max(0, row1+col1-n)
+	hi := min(row1, col1)
+	p := 0.0
+	for x := lo; x <= hi; x++ {
+		lp := hypergeomLogP(x, row1, col1, n)
+		// Small relative tolerance so tables as extreme as the observed
+		// one are not dropped because of rounding.
+		if lp <= observed+1e-7 {
+			p += math.Exp(lp)
+		}
+	}
+	return math.Min(p, 1)
+}
+
+// hypergeomLogP returns the log probability of x successes in a 2x2 table
+// with the given first row total, first column total and grand total.
+func hypergeomLogP(x, row1, col1, n int) float64 {
+	return logChoose(col1, x) + logChoose(n-col1, row1-x) - logChoose(n, row1)
+}
+
+func logChoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// ChiSquare2x2 runs Pearson's chi-square test of independence on the 2x2
+// table [[a, b], [c, d]] and returns the statistic and p-value (df 1).
+func ChiSquare2x2(a, b, c, d int) (chi2, p float64) {
+	n := float64(a + b + c + d)
+	rows := []float64{float64(a + b), float64(c + d)}
+	cols := []float64{float64(a + c), float64(b + d)}
+	if rows[0]*rows[1]*cols[0]*cols[1] == 0 {
+		return 0, math.NaN()
+	}
+	cells := [2][2]int{{a, b}, {c, d}}
+	for i := range 2 {
+		for j := range 2 {
+			e := rows[i] * cols[j] / n
+			diff := float64(cells[i][j]) - e
+			chi2 += diff * diff / e
+		}
+	}
+	return chi2, ChiSquareSF(chi2, 1)
+}
+
+// Phi returns the phi correlation coefficient of the 2x2 table
+// [[a, b], [c, d]].
+func Phi(a, b, c, d int) float64 {
+	den := math.Sqrt(float64(a+b) * float64(c+d) * float64(a+c) * float64(b+d))
+	if den == 0 {
+		return math.NaN()
+	}
+	return (float64(a)*float64(d) - float64(b)*float64(c)) / den
+}
+
+// Autocorrelation returns the sample autocorrelation of xs at the given lag.
+func Autocorrelation(xs []float64, lag int) float64 {
+	n := len(xs)
+	if lag <= 0 || n <= lag {
+		return math.NaN()
+	}
+	mean := 0.0
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(n)
+	var num, den float64
+	for i, x := range xs {
+		den += (x - mean) * (x - mean)
+		if i+lag < n {
+			num += (x - mean) * (xs[i+lag] - mean)
+		}
+	}
+	if den == 0 {
+		return math.NaN()
+	}
+	return num / den
+}
+
+// AutocorrelationP returns the two-sided p-value of an autocorrelation r over
+// n observations under the null hypothesis of independence, using the
+// large-sample approximation r ~ N(0, 1/n).
+func AutocorrelationP(r float64, n int) float64 {
+	if n == 0 || math.IsNaN(r) {
+		return math.NaN()
+	}
+	return 2 * NormalCDF(-math.Abs(r)*math.Sqrt(float64(n)))
+}
+
+// Logistic returns 1 / (1 + e^-x).
+func Logistic(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// LogisticRegression fits P(y = 1) = Logistic(intercept + slope*x) by maximum
+// likelihood and returns the coefficients with the standard error of the
+// slope. Every value is NaN when the fit doesn't exist, as when x is
+// constant or perfectly separates the outcomes.
+func LogisticRegression(xs []float64, ys []bool) (intercept, slope, slopeSE float64) {
+	nan := math.NaN()
+	if len(xs) < 2 || len(xs) != len(ys) {
+		return nan, nan, nan
+	}
+	// Newton-Raphson on the log-likelihood, solving the 2x2 system directly
+	var b0, b1 float64
+	for range 100 {
+		var g0, g1, h00, h01, h11 float64
+		for i, x := range xs {
+			p := Logistic(b0 + b1*x)
+			y := 0.0
+			if ys[i] {
+				y = 1
+			}
+			w := p * (1 - p)
+			g0 += y - p
+			g1 += (y - p) * x
+			h00 += w
+			h01 += w * x
+			h11 += w * x * x
+		}
+		det := h00*h11 - h01*h01
+		if det <= 1e-12 {
+			return nan, nan, nan
+		}
+		d0 := (h11*g0 - h01*g1) / det
+		d1 := (h00*g1 - h01*g0) / det
+		b0 += d0
+		b1 += d1
+		if math.Abs(b1) > 50 {
+			// Diverging: the outcomes are separated by x
+			return nan, nan, nan
+		}
+		if math.Abs(d0) < 1e-10 && math.Abs(d1) < 1e-10 {
+			return b0, b1, math.Sqrt(h00 / det)
+		}
+	}
+	return nan, nan, nan
+}
+
+// WaldP returns the two-sided p-value of a coefficient with the given
+// standard error.
+func WaldP(coef, se float64) float64 {
+	if math.IsNaN(coef) || se <= 0 {
+		return math.NaN()
+	}
+	return 2 * NormalCDF(-math.Abs(coef/se))
+}
+
+// OddsRatioCI returns the 95% Wald confidence interval of the odds ratio of a
+// logistic regression slope.
+func OddsRatioCI(slope, se float64) (lo, hi float64) {
+	return math.Exp(slope - zCritical95*se), math.Exp(slope + zCritical95*se)
+}
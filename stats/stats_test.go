@@ -0,0 +1,255 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+// near reports whether got is within tol of want, NaN matching NaN.
+func near(got, want, tol float64) bool {
+	if math.IsNaN(want) {
+		return math.IsNaN(got)
+	}
+	return math.Abs(got-want) <= tol
+}
+
+func TestChiSquareSF(t *testing.T) {
+	tests := []struct {
+		x    float64
+		df   int
+		want float64
+	}{
+		// 95th percentiles of the chi-square distribution
+		{3.841458820694124, 1, 0.05},
+		{5.991464547107979, 2, 0.05},
+		{18.307038053275146, 10, 0.05},
+		// With 2 degrees of freedom, the survival function is exp(-x/2)
+		{10, 2, math.Exp(-5)},
+		{0, 3, 1},
+		{1, 0, math.NaN()},
+	}
+	for _, tt := range tests {
+		if got := ChiSquareSF(tt.x, tt.df); !near(got, tt.want, 1e-9) {
+			t.Errorf("ChiSquareSF(%v, %d) = %v, want %v", tt.x, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestChiSquareFit(t *testing.T) {
+	tests := []struct {
+		observed []int
+		expected []float64
+		chi2     float64
+		df       int
+		p        float64
+	}{
+		{[]int{10, 20, 30}, []float64{20, 20, 20}, 10, 2, math.Exp(-5)},
+		{[]int{25, 25}, []float64{25, 25}, 0, 1, 1},
+		// Buckets with no expected count are ignored
+		{[]int{10, 20, 30, 0}, []float64{20, 20, 20, 0}, 10, 2, math.Exp(-5)},
+		{[]int{5}, []float64{5}, 0, 0, math.NaN()},
+	}
+	for _, tt := range tests {
+		chi2, df, p := ChiSquareFit(tt.observed, tt.expected)
+		if !near(chi2, tt.chi2, 1e-9) || df != tt.df || !near(p, tt.p, 1e-9) {
+			t.Errorf("ChiSquareFit(%v, %v) = %v, %d, %v, want %v, %d, %v",
+				tt.observed, tt.expected, chi2, df, p, tt.chi2, tt.df, tt.p)
+		}
+	}
+}
+
+func TestChiSquare2x2(t *testing.T) {
+	tests := []struct {
+		a, b, c, d int
+		chi2, p    float64
+		phi        float64
+	}{
+		{10, 20, 30, 40, 0.7936507936507936, 0.37299848361348714, -0.0890870806374748},
+		{25, 25, 25, 25, 0, 1, 0},
+		{0, 0, 30, 40, 0, math.NaN(), math.NaN()},
+	}
+	for _, tt := range tests {
+		chi2, p := ChiSquare2x2(tt.a, tt.b, tt.c, tt.d)
+		if !near(chi2, tt.chi2, 1e-9) || !near(p, tt.p, 1e-9) {
+			t.Errorf("ChiSquare2x2(%d, %d, %d, %d) = %v, %v, want %v, %v", tt.a, tt.b, tt.c, tt.d, chi2, p, tt.chi2, tt.p)
+		}
+		if phi := Phi(tt.a, tt.b, tt.c, tt.d); !near(phi, tt.phi, 1e-9) {
+			t.Errorf("Phi(%d, %d, %d, %d) = %v, want %v", tt.a, tt.b, tt.c, tt.d, phi, tt.phi)
+		}
+	}
+}
+
+func TestFisherExact(t *testing.T) {
+	tests := []struct {
+		a, b, c, d int
+		want       float64
+	}{
+		// Fisher's lady tasting tea: 34/70
+		{3, 1, 1, 3, 0.4857142857142857},
+		// Two-sided p-value given by R's fisher.test
+		{1, 9, 11, 3, 0.002759456185220083},
+		// Both tables as extreme as the observed one: 2/252
+		{0, 5, 5, 0, 0.007936507936507936},
+		{2, 2, 2, 2, 1},
+		{0, 0, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		if got := FisherExact(tt.a, tt.b, tt.c, tt.d); !near(got, tt.want, 1e-9) {
+			t.Errorf("FisherExact(%d, %d, %d, %d) = %v, want %v", tt.a, tt.b, tt.c, tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestWilsonCI(t *testing.T) {
+	// Intervals from Newcombe (1998), Statistics in Medicine 17, table II
+	tests := []struct {
+		x, n   int
+		lo, hi float64
+	}{
+		{81, 263, 0.2553, 0.3662},
+		{15, 148, 0.0624, 0.1605},
+		{0, 20, 0, 0.1611},
+		{1, 29, 0.0061, 0.1718},
+		{0, 0, math.NaN(), math.NaN()},
+	}
+	for _, tt := range tests {
+		lo, hi := WilsonCI(tt.x, tt.n)
+		if !near(lo, tt.lo, 5e-5) || !near(hi, tt.hi, 5e-5) {
+			t.Errorf("WilsonCI(%d, %d) = %.4f, %.4f, want %.4f, %.4f", tt.x, tt.n, lo, hi, tt.lo, tt.hi)
+		}
+	}
+}
+
+func TestTwoProportionZ(t *testing.T) {
+	tests := []struct {
+		x1, n1, x2, n2 int
+		z, p           float64
+	}{
+		// Pooled rate 0.5, so the standard error is sqrt(0.25 * 0.02)
+		{60, 100, 40, 100, 2 * math.Sqrt2, 0.004677734981047265},
+		{40, 100, 60, 100, -2 * math.Sqrt2, 0.004677734981047265},
+		{50, 100, 50, 100, 0, 1},
+		// Every trial succeeded in both groups
+		{10, 10, 20, 20, 0, 1},
+		{1, 10, 0, 0, 0, math.NaN()},
+	}
+	for _, tt := range tests {
+		z, p := TwoProportionZ(tt.x1, tt.n1, tt.x2, tt.n2)
+		if !near(z, tt.z, 1e-9) || !near(p, tt.p, 1e-9) {
+			t.Errorf("TwoProportionZ(%d, %d, %d, %d) = %v, %v, want %v, %v", tt.x1, tt.n1, tt.x2, tt.n2, z, p, tt.z, tt.p)
+		}
+	}
+}
+
+func TestChiSquareHomogeneity(t *testing.T) {
+	// Two groups give the same statistic as the 2x2 test
+	chi2, df, p, minExpected := ChiSquareHomogeneity([]int{10, 30}, []int{30, 70})
+	if !near(chi2, 0.7936507936507936, 1e-9) || df != 1 || !near(p, 0.37299848361348714, 1e-9) || !near(minExpected, 12, 1e-9) {
+		t.Errorf("ChiSquareHomogeneity = %v, %d, %v, %v, want 0.79365, 1, 0.37300, 12", chi2, df, p, minExpected)
+	}
+	// Groups without trials are ignored
+	if _, df, _, _ := ChiSquareHomogeneity([]int{10, 0, 30}, []int{30, 0, 70}); df != 1 {
+		t.Errorf("ChiSquareHomogeneity with an empty group has df %d, want 1", df)
+	}
+	if _, _, p, _ := ChiSquareHomogeneity([]int{5, 5}, []int{5, 5}); !math.IsNaN(p) {
+		t.Errorf("ChiSquareHomogeneity with only successes has p %v, want NaN", p)
+	}
+}
+
+func TestAutocorrelation(t *testing.T) {
+	tests := []struct {
+		xs   []float64
+		lag  int
+		want float64
+	}{
+		{[]float64{1, 2, 3, 4, 5}, 1, 0.4},
+		{[]float64{1, 2, 3, 4, 5}, 2, -0.1},
+		{[]float64{1, 0, 1, 0, 1, 0}, 1, -5.0 / 6},
+		{[]float64{1, 0, 1, 0, 1, 0}, 2, 4.0 / 6},
+		{[]float64{1, 1, 1}, 1, math.NaN()},
+		{[]float64{1, 2}, 0, math.NaN()},
+		{[]float64{1, 2}, 2, math.NaN()},
+	}
+	for _, tt := range tests {
+		if got := Autocorrelation(tt.xs, tt.lag); !near(got, tt.want, 1e-12) {
+			t.Errorf("Autocorrelation(%v, %d) = %v, want %v", tt.xs, tt.lag, got, tt.want)
+		}
+	}
+
+	// r = 0.2 over 100 observations is 2 standard errors from 0
+	if got, want := AutocorrelationP(0.2, 100), 2*NormalCDF(-2); !near(got, want, 1e-12) {
+		t.Errorf("AutocorrelationP(0.2, 100) = %v, want %v", got, want)
+	}
+}
+
+func TestLogisticRegression(t *testing.T) {
+	bools := func(bits ...int) []bool {
+		ys := make([]bool, len(bits))
+		for i, b := range bits {
+			ys[i] = b == 1
+		}
+		return ys
+	}
+	tests := []struct {
+		name                      string
+		xs                        []float64
+		ys                        []bool
+		intercept, slope, slopeSE float64
+	}{
+		{
+			// With a binary x the model is saturated: the intercept is the
+			// log odds at x = 0, the slope the log odds ratio, and its
+			// standard error sqrt(1/a + 1/b + 1/c + 1/d).
+			name:      "binary",
+			xs:        []float64{0, 0, 0, 0, 1, 1, 1, 1},
+			ys:        bools(1, 1, 1, 0, 1, 0, 0, 0),
+			intercept: math.Log(3),
+			slope:     -2 * math.Log(3),
+			slopeSE:   math.Sqrt(1.0/3 + 1 + 1 + 1.0/3),
+		},
+		{
+			name:      "no effect",
+			xs:        []float64{0, 0, 1, 1},
+			ys:        bools(1, 0, 1, 0),
+			intercept: 0,
+			slope:     0,
+			slopeSE:   2,
+		},
+		{
+			name:      "separated",
+			xs:        []float64{0, 1, 2, 3},
+			ys:        bools(0, 0, 1, 1),
+			intercept: math.NaN(), slope: math.NaN(), slopeSE: math.NaN(),
+		},
+		{
+			name:      "constant",
+			xs:        []float64{1, 1, 1, 1},
+			ys:        bools(0, 1, 0, 1),
+			intercept: math.NaN(), slope: math.NaN(), slopeSE: math.NaN(),
+		},
+		{
+			name:      "too short",
+			xs:        []float64{1},
+			ys:        bools(1),
+			intercept: math.NaN(), slope: math.NaN(), slopeSE: math.NaN(),
+		},
+	}
+	for _, tt := range tests {
+		intercept, slope, se := LogisticRegression(tt.xs, tt.ys)
+		if !near(intercept, tt.intercept, 1e-6) || !near(slope, tt.slope, 1e-6) || !near(se, tt.slopeSE, 1e-6) {
+			t.Errorf("%s: LogisticRegression = %v, %v, %v, want %v, %v, %v",
+				tt.name, intercept, slope, se, tt.intercept, tt.slope, tt.slopeSE)
+		}
+	}
+}
+
+func TestMeanVariance(t *testing.T) {
+	// One observation of 0, two of 1 and one of 2
+	mean, variance := MeanVariance([]int{1, 2, 1})
+	if mean != 1 || variance != 0.5 {
+		t.Errorf("MeanVariance = %v, %v, want 1, 0.5", mean, variance)
+	}
+	if mean, variance := MeanVariance(nil); mean != 0 || variance != 0 {
+		t.Errorf("MeanVariance(nil) = %v, %v, want 0, 0", mean, variance)
+	}
+}